@@ -0,0 +1,273 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for finding the CommitGuard tags of a repository
+// and reading the metadata from their annotations.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// branchesTrailer is the trailer in the annotation of a CommitGuard tag that lists the base
+// branches the guard applies to, as comma separated globs. Guards without it apply to pull
+// requests targeting any base branch. The key is matched ignoring case, e.g.:
+//
+//	CommitGuard-Branches: main, release/*
+const branchesTrailer = "commitguard-branches"
+
+// expiresTrailer is the trailer in the annotation of a CommitGuard tag that sets when the
+// guard expires, as a date (the start of the day in UTC) or an RFC 3339 timestamp. It takes
+// precedence over an expiry in the tag name. Expired guards are no longer enforced and are
+// retired by the maintenance mode, e.g.:
+//
+//	CommitGuard-Expires: 2026-12-31
+const expiresTrailer = "commitguard-expires"
+
+// guard is a CommitGuard tag, marking a commit that must be in the history of pull requests.
+type guard struct {
+	// Tag is the name of the tag.
+	Tag string
+
+	// SHA is the SHA of the commit the tag points to, which is the required commit.
+	SHA string
+
+	// ObjectSHA is the SHA of the object the tag ref points to: the annotated tag object, or
+	// the commit for lightweight tags.
+	ObjectSHA string
+
+	// Timestamp is the unix timestamp from the tag name.
+	Timestamp int
+
+	// Expires is when the guard expires, or the zero value if it never does.
+	Expires time.Time
+
+	// Branches are the globs of the base branches the guard applies to. The guard applies
+	// to every base branch when this is empty.
+	Branches []string
+
+	// Message is the annotation of the tag, explaining why the commit is required, without
+	// the CommitGuard trailers. This is empty for lightweight tags.
+	Message string
+
+	// Tagger is the name of the person who created the tag. This is empty for lightweight
+	// tags.
+	Tagger string
+
+	// Date is when the tag was created. This is the zero value for lightweight tags.
+	Date time.Time
+}
+
+// appliesTo returns true if the guard applies to pull requests targeting the base branch.
+func (g *guard) appliesTo(branch string) bool {
+	if len(g.Branches) == 0 {
+		return true
+	}
+
+	for _, pattern := range g.Branches {
+		if ok, err := doublestar.Match(pattern, branch); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns true if the guard expired at or before now.
+func (g *guard) expired(now time.Time) bool {
+	return !g.Expires.IsZero() && !now.Before(g.Expires)
+}
+
+// parseGuardName returns the timestamp and the expiry, if any, from the name of a
+// CommitGuard tag and whether or not the name is one of a CommitGuard tag. Names are either
+// "commitguard-<unix timestamp>" or "commitguard-<unix timestamp>-<unix expiry>".
+func parseGuardName(name string) (timestamp int, expires time.Time, ok bool) {
+	lower := strings.ToLower(name)
+	if !strings.HasPrefix(lower, tagPrefix) {
+		return 0, time.Time{}, false
+	}
+
+	stringTimestamp, stringExpires, hasExpiry := strings.Cut(strings.TrimPrefix(lower, tagPrefix), "-")
+
+	timestamp, err := strconv.Atoi(stringTimestamp)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+
+	if hasExpiry {
+		expiry, err := strconv.ParseInt(stringExpires, 10, 64)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		expires = time.Unix(expiry, 0).UTC()
+	}
+	return timestamp, expires, true
+}
+
+// listGuards looks through a repository's tags and returns every CommitGuard tag. Only the
+// name of the tags and the commit they point to are read, which the list of tags already
+// includes, see fetchAnnotation for the rest of the metadata. Unless includeExpired is true,
+// guards that expired at or before now according to their name are left out.
+func listGuards(ctx context.Context, client *github.Client, org, repo string, now time.Time, includeExpired bool) ([]*guard, error) { //nolint:lll // Why: Function signature.
+	tagPage := 1
+	tagsPerPage := 100
+
+	var guards []*guard
+	for tagPage != 0 {
+		tags, res, err := client.Repositories.ListTags(ctx, org, repo, &github.ListOptions{
+			PerPage: tagsPerPage,
+			Page:    tagPage,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "list tags page %d", tagPage)
+		}
+
+		for i := range tags {
+			timestamp, expires, ok := parseGuardName(tags[i].GetName())
+			if !ok || tags[i].GetCommit().GetSHA() == "" {
+				continue
+			}
+
+			g := &guard{
+				Tag:       tags[i].GetName(),
+				SHA:       tags[i].GetCommit().GetSHA(),
+				Timestamp: timestamp,
+				Expires:   expires,
+			}
+			if !includeExpired && g.expired(now) {
+				actions.Infof("ignoring CommitGuard tag %q, it expired on %s", g.Tag, g.Expires.Format(time.RFC3339))
+				continue
+			}
+			guards = append(guards, g)
+		}
+
+		// Set it to the next page. Once we're done paginating this value should be zero which
+		// the loop will recognize and exit.
+		tagPage = res.NextPage
+	}
+
+	return guards, nil
+}
+
+// getGuard returns the CommitGuard tag with the given name, reading the metadata from its
+// annotation if it is an annotated tag.
+func getGuard(ctx context.Context, client *github.Client, org, repo, name string) (*guard, error) {
+	timestamp, expires, ok := parseGuardName(name)
+	if !ok {
+		return nil, errors.Errorf("tag %q is not a CommitGuard tag", name)
+	}
+
+	g := &guard{Tag: name, Timestamp: timestamp, Expires: expires}
+	if err := g.fetchAnnotation(ctx, client, org, repo); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// fetchAnnotation reads the object the tag of the guard points to and, for annotated tags,
+// the metadata from its annotation. This takes up to two requests, so it is only done for
+// the guards that need it.
+func (g *guard) fetchAnnotation(ctx context.Context, client *github.Client, org, repo string) error {
+	ref, _, err := client.Git.GetRef(ctx, org, repo, "tags/"+g.Tag)
+	if err != nil {
+		return errors.Wrapf(err, "get ref of tag %q", g.Tag)
+	}
+
+	g.SHA = ref.GetObject().GetSHA()
+	g.ObjectSHA = ref.GetObject().GetSHA()
+	if ref.GetObject().GetType() != "tag" {
+		// Lightweight tags point directly to the commit and have no annotation.
+		return nil
+	}
+
+	tag, _, err := client.Git.GetTag(ctx, org, repo, ref.GetObject().GetSHA())
+	if err != nil {
+		return errors.Wrapf(err, "get annotated tag %q", g.Tag)
+	}
+
+	g.SHA = tag.GetObject().GetSHA()
+	g.Tagger = tag.GetTagger().GetName()
+	g.Date = tag.GetTagger().GetDate().Time
+	g.readAnnotation(tag.GetMessage())
+	return nil
+}
+
+// readAnnotation sets the metadata of the guard from the trailers in the message of its
+// annotated tag.
+func (g *guard) readAnnotation(message string) {
+	trailers := parseTrailers(message)
+	for _, pattern := range strings.Split(trailers[branchesTrailer], ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			g.Branches = append(g.Branches, pattern)
+		}
+	}
+
+	if value := trailers[expiresTrailer]; value != "" {
+		expires, err := parseExpiry(value)
+		if err != nil {
+			actions.Warningf("ignoring invalid expiry of CommitGuard tag %q: %v", g.Tag, err)
+		} else {
+			g.Expires = expires
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		// Trailers of this action share the prefix of its tags, e.g. CommitGuard-Branches.
+		if key, ok := trailerKey(line); !ok || !strings.HasPrefix(key, tagPrefix) {
+			lines = append(lines, line)
+		}
+	}
+	g.Message = strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// parseTrailers returns the "Key: value" trailers in message, with the keys lower cased.
+// Unlike git, trailers are accepted anywhere in the message since tag annotations are often
+// a single paragraph.
+func parseTrailers(message string) map[string]string {
+	trailers := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for scanner.Scan() {
+		key, ok := trailerKey(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		_, value, _ := strings.Cut(scanner.Text(), ":")
+		trailers[key] = strings.TrimSpace(value)
+	}
+	return trailers
+}
+
+// trailerKey returns the lower cased key of line and whether or not line is a "Key: value"
+// trailer.
+func trailerKey(line string) (string, bool) {
+	key, _, ok := strings.Cut(line, ":")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", false
+	}
+	return strings.ToLower(key), true
+}
+
+// parseExpiry parses the value of the expiresTrailer, either a date or an RFC 3339
+// timestamp.
+func parseExpiry(value string) (time.Time, error) {
+	if expires, err := time.Parse(time.DateOnly, value); err == nil {
+		return expires, nil
+	}
+
+	expires, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("expected a date (YYYY-MM-DD) or an RFC 3339 timestamp, got %q", value)
+	}
+	return expires, nil
+}
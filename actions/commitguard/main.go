@@ -6,7 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
@@ -39,7 +39,16 @@ const pullRequestSharedActionsWorkflowFile = "pull_request-shared-actions.yaml"
 //	unset CM_TAG_NAME
 //
 // Make sure you give your created tag a good description as to why that place in
-// history is important to your repository.
+// history is important to your repository. To only guard pull requests targeting some
+// base branches, list them in the description with the branchesTrailer, and to stop
+// guarding after some time, set the expiresTrailer, e.g.:
+//
+//	CommitGuard-Branches: main, release/*
+//	CommitGuard-Expires: 2026-12-31
+//
+// The expiry can also be part of the tag name, as a second unix timestamp:
+//
+//	CM_TAG_NAME="CommitGuard-$(date +%s)-$(date -d '+90 days' +%s)"
 const tagPrefix = "commitguard-"
 
 func main() {
@@ -78,6 +87,8 @@ func RunAction(ctx context.Context, client *github.Client, actionCtx *actions.Gi
 		return runOnPullRequest(ctx, client, actionCtx)
 	case "create":
 		return runOnCreate(ctx, client, actionCtx)
+	case "schedule", "workflow_dispatch":
+		return runMaintenance(ctx, client, actionCtx)
 	default:
 		return fmt.Errorf("unknown event type %q", en)
 	}
@@ -97,6 +108,16 @@ func runOnCreate(ctx context.Context, client *github.Client, actionCtx *actions.
 		return nil
 	}
 
+	g, err := getGuard(ctx, client, create.Repository.Owner.Login, create.Repository.Name, create.Ref)
+	if err != nil {
+		return errors.Wrap(err, "get created CommitGuard tag")
+	}
+
+	if g.expired(time.Now()) {
+		actions.Infof("CommitGuard tag %q already expired, not rerunning checks", g.Tag)
+		return nil
+	}
+
 	pulls, err := gh.ListAllPullRequests(ctx, client, create.Repository.Owner.Login, create.Repository.Name, "open")
 	if err != nil {
 		return errors.Wrap(err, "list all open pull requests")
@@ -109,10 +130,11 @@ func runOnCreate(ctx context.Context, client *github.Client, actionCtx *actions.
 
 	// Transform the open pull requests into a map that way it makes finding them easier
 	// below when we're trying to figure out which workflows still have open pull requests
-	// so they can be reran.
+	// so they can be reran. Pull requests targeting base branches the guard doesn't apply
+	// to are left out, their checks can't change.
 	openPulls := make(map[int]struct{})
 	for i := range pulls {
-		if pulls[i].Number == nil {
+		if pulls[i].Number == nil || !g.appliesTo(pulls[i].GetBase().GetRef()) {
 			continue
 		}
 		openPulls[*pulls[i].Number] = struct{}{}
@@ -171,78 +193,76 @@ func runOnPullRequest(ctx context.Context, client *github.Client, actionCtx *act
 		return errors.Wrap(err, "parse event payload")
 	}
 
-	requiredSHA, err := findCommitGuardRequiredSHA(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name)
+	guards, err := findCommitGuards(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name)
 	if err != nil {
-		return errors.Wrap(err, "get required commit sha from inspecting tags")
+		return errors.Wrap(err, "get required commits from inspecting tags")
 	}
 
-	actions.Infof("parsed necessary information:\nbranch: [%s]\nrequired commit sha: [%s]", pr.Head.Ref, requiredSHA)
+	actions.Infof("parsed necessary information:\nbranch: [%s]\nbase branch: [%s]\nCommitGuard tags: [%d]",
+		pr.Head.Ref, pr.Base.Ref, len(guards))
 
-	if requiredSHA == "" {
+	if len(guards) == 0 {
 		actions.Infof("no CommitGuard tags found, skipping check")
-		return nil
+		return reportMissingGuards(ctx, client, pr, nil)
 	}
 
-	// What is happening here is explain in this stackoverflow answer, specifically
-	// "Workaround 2": https://stackoverflow.com/a/23970412
-	comparison, _, err := client.Repositories.CompareCommits(ctx, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Head.Ref, requiredSHA, nil)
+	missing, err := missingGuards(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Head.Ref, pr.Base.Ref, guards, time.Now())
 	if err != nil {
-		return errors.Wrap(err, "call to github api to compare head ref to required commit failed")
+		return err
 	}
+	return reportMissingGuards(ctx, client, pr, missing)
+}
 
-	if status := comparison.GetStatus(); status == "diverged" || status == "ahead" {
-		actions.Infof("comparison status: [%s]", status)
-		return errors.New("branch does not contain required commit sha, please rebase")
+// findCommitGuards looks through a repositories tags to get every CommitGuard tag that
+// has not expired according to its name, from oldest to newest.
+func findCommitGuards(ctx context.Context, client *github.Client, org, repo string) ([]*guard, error) {
+	guards, err := listGuards(ctx, client, org, repo, time.Now(), false)
+	if err != nil {
+		return nil, err
 	}
-	actions.Infof("branch contains required commit")
 
-	return nil
+	sort.SliceStable(guards, func(i, j int) bool {
+		return guards[i].Timestamp < guards[j].Timestamp
+	})
+	return guards, nil
 }
 
-// findCommitGuardRequiredSHA looks through a repositories tags to get the most recent CommitGuard
-// tag's corresponding commit SHA.
-func findCommitGuardRequiredSHA(ctx context.Context, client *github.Client, org, repo string) (string, error) {
-	tagPage := 1
-	tagsPerPage := 100
-
-	var requiredCommitSHA string
-	var mostRecentTimestamp int
-
-	for tagPage != 0 {
-		tags, res, err := client.Repositories.ListTags(ctx, org, repo, &github.ListOptions{
-			PerPage: tagsPerPage,
-			Page:    tagPage,
-		})
+// missingGuards returns the guards whose commit is not in the history of headRef and that
+// apply to pull requests targeting baseBranch at now. The annotations of the tags, which can
+// limit the base branches or set the expiry of a guard, are only fetched for the guards
+// whose commit is missing, since the others pass either way.
+func missingGuards(ctx context.Context, client *github.Client, org, repo, headRef, baseBranch string, guards []*guard,
+	now time.Time) ([]*guard, error) {
+	var missing []*guard
+	for _, g := range guards {
+		// What is happening here is explain in this stackoverflow answer, specifically
+		// "Workaround 2": https://stackoverflow.com/a/23970412
+		comparison, _, err := client.Repositories.CompareCommits(ctx, org, repo, headRef, g.SHA, nil)
 		if err != nil {
-			return "", errors.Wrapf(err, "list tags page %d", tagPage)
+			return nil, errors.Wrapf(err, "call to github api to compare head ref to required commit of %q failed", g.Tag)
 		}
 
-		for i := range tags {
-			tag := tags[i]
-
-			if tag.Name == nil || tag.Commit == nil || tag.Commit.SHA == nil {
-				continue
-			}
-
-			if strings.HasPrefix(strings.ToLower(*tag.Name), tagPrefix) {
-				stringTimestamp := strings.TrimPrefix(strings.ToLower(*tag.Name), tagPrefix)
+		status := comparison.GetStatus()
+		if status != "diverged" && status != "ahead" {
+			actions.Infof("branch contains required commit of %q", g.Tag)
+			continue
+		}
+		actions.Infof("comparison status for %q: [%s]", g.Tag, status)
 
-				currentTimestamp, err := strconv.Atoi(stringTimestamp) //nolint:govet // Why: It's okay to shadow the error variable here.
-				if err != nil {
-					continue
-				}
+		if err := g.fetchAnnotation(ctx, client, org, repo); err != nil {
+			return nil, err
+		}
 
-				if currentTimestamp > mostRecentTimestamp {
-					mostRecentTimestamp = currentTimestamp
-					requiredCommitSHA = *tag.Commit.SHA
-				}
-			}
+		if !g.appliesTo(baseBranch) {
+			actions.Infof("ignoring CommitGuard tag %q, it only applies to base branches %s", g.Tag, strings.Join(g.Branches, ", "))
+			continue
 		}
 
-		// Set it to the next page. Once we're done paginating this value should be zero which
-		// the loop will recognize and exit.
-		tagPage = res.NextPage
+		if g.expired(now) {
+			actions.Infof("ignoring CommitGuard tag %q, it expired on %s", g.Tag, g.Expires.Format(time.RFC3339))
+			continue
+		}
+		missing = append(missing, g)
 	}
-
-	return requiredCommitSHA, nil
+	return missing, nil
 }
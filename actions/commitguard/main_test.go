@@ -0,0 +1,442 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	actions "github.com/sethvargo/go-githubactions"
+	"gotest.tools/v3/assert"
+)
+
+// newTestClient returns a GitHub client that sends every request to handler instead of the
+// GitHub API.
+func newTestClient(t *testing.T, handler http.Handler) *github.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL + "/")
+	assert.NilError(t, err)
+
+	client := github.NewClient(nil)
+	client.BaseURL = baseURL
+	return client
+}
+
+func Test_parseGuardName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       string
+		timestamp int
+		expires   time.Time
+		ok        bool
+	}{
+		{
+			name:      "lower case",
+			tag:       "commitguard-1760659200",
+			timestamp: 1760659200,
+			ok:        true,
+		},
+		{
+			name:      "mixed case",
+			tag:       "CommitGuard-1760659200",
+			timestamp: 1760659200,
+			ok:        true,
+		},
+		{
+			name:      "with expiry",
+			tag:       "CommitGuard-1760659200-1768435200",
+			timestamp: 1760659200,
+			expires:   time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+			ok:        true,
+		},
+		{
+			name: "invalid expiry",
+			tag:  "commitguard-1760659200-soon",
+		},
+		{
+			name: "not a timestamp",
+			tag:  "commitguard-security-fix",
+		},
+		{
+			name: "not a guard",
+			tag:  "v1.2.3",
+		},
+		{
+			name: "archived guard",
+			tag:  "archived/commitguard-1760659200",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, expires, ok := parseGuardName(tt.tag)
+			assert.Equal(t, timestamp, tt.timestamp)
+			assert.Assert(t, expires.Equal(tt.expires), "expires %s, want %s", expires, tt.expires)
+			assert.Equal(t, ok, tt.ok)
+		})
+	}
+}
+
+func Test_guard_readAnnotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		branch     string
+		applies    bool
+		message    string
+		expires    time.Time
+	}{
+		{
+			name:       "no annotation applies to every branch",
+			annotation: "",
+			branch:     "release/2026.10",
+			applies:    true,
+		},
+		{
+			name:       "annotation without branches applies to every branch",
+			annotation: "Fix for CVE-2026-1234, every branch must contain it.\n",
+			branch:     "main",
+			applies:    true,
+			message:    "Fix for CVE-2026-1234, every branch must contain it.",
+		},
+		{
+			name: "listed branch",
+			annotation: "Schema migration that must not be reverted.\n\n" +
+				"Refs: DT-123\nCommitGuard-Branches: main, develop\n",
+			branch:  "develop",
+			applies: true,
+			message: "Schema migration that must not be reverted.\n\nRefs: DT-123",
+		},
+		{
+			name:       "branch glob",
+			annotation: "commitguard-branches: release/*",
+			branch:     "release/2026.10",
+			applies:    true,
+		},
+		{
+			name:       "other branch",
+			annotation: "CommitGuard-Branches: release/2026.10",
+			branch:     "main",
+			applies:    false,
+		},
+		{
+			name:       "expiry date",
+			annotation: "Temporary guard for the migration.\nCommitGuard-Expires: 2026-12-31",
+			branch:     "main",
+			applies:    true,
+			message:    "Temporary guard for the migration.",
+			expires:    time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "expiry timestamp overrides the name",
+			annotation: "CommitGuard-Expires: 2026-11-01T12:00:00Z",
+			branch:     "main",
+			applies:    true,
+			expires:    time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "invalid expiry is ignored",
+			annotation: "CommitGuard-Expires: next week",
+			branch:     "main",
+			applies:    true,
+			expires:    time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &guard{Tag: "commitguard-1760659200-1768435200", Expires: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)}
+			g.readAnnotation(tt.annotation)
+			assert.Equal(t, g.appliesTo(tt.branch), tt.applies)
+			assert.Equal(t, g.Message, tt.message)
+			if !tt.expires.IsZero() {
+				assert.Assert(t, g.Expires.Equal(tt.expires), "expires %s, want %s", g.Expires, tt.expires)
+			}
+		})
+	}
+}
+
+func Test_guard_expired(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{
+			name: "no expiry",
+		},
+		{
+			name:    "expires later",
+			expires: now.Add(time.Hour),
+		},
+		{
+			name:    "expires now",
+			expires: now,
+			want:    true,
+		},
+		{
+			name:    "expired",
+			expires: now.Add(-time.Hour),
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &guard{Tag: "commitguard-1760659200", Expires: tt.expires}
+			assert.Equal(t, g.expired(now), tt.want)
+		})
+	}
+}
+
+func Test_listGuards(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/getoutreach/actions/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"name":"v1.2.3","commit":{"sha":"1111111111111111111111111111111111111111"}},
+			{"name":"CommitGuard-1760745600","commit":{"sha":"f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4"}},
+			{"name":"commitguard-1760659200-1759276800","commit":{"sha":"2222222222222222222222222222222222222222"}},
+			{"name":"commitguard-1760659200","commit":{"sha":"6dcb09b5b57875f334f61aebed695e2e4193db5e"}}
+		]`)
+	})
+	// Any other request, e.g. for the annotation of a tag, fails.
+	client := newTestClient(t, mux)
+
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	guards, err := listGuards(context.Background(), client, "getoutreach", "actions", now, false)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, guards, []*guard{
+		{Tag: "CommitGuard-1760745600", SHA: "f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4", Timestamp: 1760745600},
+		{Tag: "commitguard-1760659200", SHA: "6dcb09b5b57875f334f61aebed695e2e4193db5e", Timestamp: 1760659200},
+	})
+}
+
+func Test_missingGuards(t *testing.T) {
+	// Commits of the guards the branch does not contain, and the annotations of their tags.
+	missing := map[string]string{
+		"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb": "",
+		"cccccccccccccccccccccccccccccccccccccccc": "CommitGuard-Branches: release/*",
+		"dddddddddddddddddddddddddddddddddddddddd": "CommitGuard-Expires: 2026-10-01",
+		"eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee": "Fix for CVE-2026-1234.\nCommitGuard-Branches: main",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/getoutreach/actions/compare/{basehead}", func(w http.ResponseWriter, r *http.Request) {
+		_, sha, _ := strings.Cut(r.PathValue("basehead"), "...")
+		status := "behind"
+		if _, ok := missing[sha]; ok {
+			status = "diverged"
+		}
+		fmt.Fprintf(w, `{"status":%q}`, status)
+	})
+	mux.HandleFunc("GET /repos/getoutreach/actions/git/ref/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		sha, ok := strings.CutPrefix(r.PathValue("tag"), "commitguard-")
+		if _, isMissing := missing[sha]; !ok || !isMissing {
+			t.Errorf("fetched the annotation of %q, whose commit the branch contains", r.PathValue("tag"))
+		}
+
+		if missing[sha] == "" {
+			fmt.Fprintf(w, `{"object":{"type":"commit","sha":%q}}`, sha)
+			return
+		}
+		fmt.Fprintf(w, `{"object":{"type":"tag","sha":"tag-%s"}}`, sha)
+	})
+	mux.HandleFunc("GET /repos/getoutreach/actions/git/tags/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		sha := strings.TrimPrefix(r.PathValue("sha"), "tag-")
+		fmt.Fprintf(w, `{"message":%q,"object":{"type":"commit","sha":%q},"tagger":{"name":"Jane Doe"}}`, missing[sha], sha)
+	})
+	client := newTestClient(t, mux)
+
+	// The tag names contain the commit SHAs to keep the fake API simple.
+	var guards []*guard
+	for _, sha := range []string{
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		"cccccccccccccccccccccccccccccccccccccccc",
+		"dddddddddddddddddddddddddddddddddddddddd",
+		"eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee",
+	} {
+		guards = append(guards, &guard{Tag: "commitguard-" + sha, SHA: sha})
+	}
+
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	got, err := missingGuards(context.Background(), client, "getoutreach", "actions", "feature", "main", guards, now)
+	assert.NilError(t, err)
+
+	tags := make([]string, 0, len(got))
+	for _, g := range got {
+		tags = append(tags, g.Tag)
+	}
+	assert.DeepEqual(t, tags, []string{
+		"commitguard-bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		"commitguard-eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee",
+	})
+	assert.Equal(t, got[1].Message, "Fix for CVE-2026-1234.")
+	assert.Equal(t, got[1].Tagger, "Jane Doe")
+}
+
+func Test_missingGuardsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing []*guard
+		errMsg  string
+	}{
+		{
+			name: "nothing missing",
+		},
+		{
+			name: "reports every missing commit",
+			missing: []*guard{
+				{
+					Tag:     "commitguard-1760659200",
+					SHA:     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+					Message: "Fix for CVE-2026-1234.\n\nEvery branch must contain it.",
+					Tagger:  "Jane Doe",
+					Date:    time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+				},
+				{Tag: "CommitGuard-1760745600", SHA: "f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4"},
+			},
+			errMsg: "branch does not contain 2 required commit(s), please rebase onto main:\n" +
+				"- 6dcb09b5b57875f334f61aebed695e2e4193db5e (commitguard-1760659200, tagged by Jane Doe on 2026-10-17)\n" +
+				"  Fix for CVE-2026-1234.\n  \n  Every branch must contain it.\n" +
+				"- f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4 (CommitGuard-1760745600)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := missingGuardsError("main", tt.missing)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func Test_missingGuardsMarkdown(t *testing.T) {
+	got := missingGuardsMarkdown("main", []*guard{
+		{
+			Tag:     "commitguard-1760659200",
+			SHA:     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			Message: "Fix for CVE-2026-1234.\n\nEvery branch must contain it.",
+			Tagger:  "Jane Doe",
+			Date:    time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC),
+		},
+		{Tag: "CommitGuard-1760745600", SHA: "f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4"},
+	})
+
+	assert.Equal(t, got, `### :x: Branch is missing required commits
+
+Pull requests targeting `+"`main`"+` must contain 2 commit(s) that this branch does not, please rebase onto `+"`main`"+`.
+
+#### `+"`commitguard-1760659200` (`6dcb09b5b57875f334f61aebed695e2e4193db5e`)"+`
+
+_Tagged by Jane Doe on 2026-10-17._
+
+> Fix for CVE-2026-1234.
+> 
+> Every branch must contain it.
+
+#### `+"`CommitGuard-1760745600` (`f3a2b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4`)"+`
+`)
+}
+
+func Test_runMaintenance(t *testing.T) {
+	// Objects the tags point to. Every tag expired according to its name, except for
+	// commitguard-5, and reading commitguard-4 fails.
+	refs := map[string]string{
+		"commitguard-1-1759276800":          "1111111111111111111111111111111111111111",
+		"commitguard-2-1759276800":          "2222222222222222222222222222222222222222",
+		"archived/commitguard-2-1759276800": "2222222222222222222222222222222222222222",
+		"commitguard-3-1759276800":          "3333333333333333333333333333333333333333",
+		"archived/commitguard-3-1759276800": "4444444444444444444444444444444444444444",
+		"commitguard-5":                     "5555555555555555555555555555555555555555",
+	}
+
+	var deleted []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/getoutreach/actions/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"name":"commitguard-1-1759276800","commit":{"sha":"1111111111111111111111111111111111111111"}},
+			{"name":"commitguard-2-1759276800","commit":{"sha":"2222222222222222222222222222222222222222"}},
+			{"name":"commitguard-3-1759276800","commit":{"sha":"3333333333333333333333333333333333333333"}},
+			{"name":"commitguard-4-1759276800","commit":{"sha":"4444444444444444444444444444444444444444"}},
+			{"name":"commitguard-5","commit":{"sha":"5555555555555555555555555555555555555555"}}
+		]`)
+	})
+	mux.HandleFunc("GET /repos/getoutreach/actions/git/ref/tags/{tag...}", func(w http.ResponseWriter, r *http.Request) {
+		sha, ok := refs[r.PathValue("tag")]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"object":{"type":"commit","sha":%q}}`, sha)
+	})
+	mux.HandleFunc("POST /repos/getoutreach/actions/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		var ref github.CreateRef
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&ref))
+		if _, ok := refs[strings.TrimPrefix(ref.Ref, "refs/tags/")]; ok {
+			http.Error(w, `{"message":"Reference already exists"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("DELETE /repos/getoutreach/actions/git/refs/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.PathValue("tag"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client := newTestClient(t, mux)
+
+	t.Setenv(retireGuardsEnv, retireArchive)
+	err := runMaintenance(context.Background(), client, &actions.GitHubContext{Repository: "getoutreach/actions"})
+	assert.ErrorContains(t, err, "unable to maintain 2 CommitGuard tag(s)")
+	assert.ErrorContains(t, err, `get ref of tag "commitguard-4-1759276800"`)
+	assert.ErrorContains(t, err, `tag "archived/commitguard-3-1759276800" already exists`)
+	assert.DeepEqual(t, deleted, []string{"commitguard-1-1759276800", "commitguard-2-1759276800"})
+}
+
+func Test_retiredGuardsMarkdown(t *testing.T) {
+	expired := []*guard{
+		{
+			Tag:     "commitguard-1760659200",
+			SHA:     "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			Expires: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	tests := []struct {
+		name string
+		mode string
+		want string
+	}{
+		{
+			name: "list only",
+			want: "### Expired CommitGuard tags\n\n| Tag | Commit | Expired |\n|---|---|---|\n" +
+				"| `commitguard-1760659200` | `6dcb09b5b57875f334f61aebed695e2e4193db5e` | 2026-10-01 |\n\n" +
+				"Set `RETIRE_GUARDS` to `delete` or `archive` to retire these tags.\n",
+		},
+		{
+			name: "archive",
+			mode: retireArchive,
+			want: "### Archived CommitGuard tags\n\n| Tag | Commit | Expired |\n|---|---|---|\n" +
+				"| `commitguard-1760659200` → `archived/commitguard-1760659200` | `6dcb09b5b57875f334f61aebed695e2e4193db5e` | 2026-10-01 |\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, retiredGuardsMarkdown(tt.mode, expired), tt.want)
+		})
+	}
+}
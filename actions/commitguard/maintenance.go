@@ -0,0 +1,189 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the maintenance mode of the action, which retires the
+// CommitGuard tags that have expired.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// retireGuardsEnv is the environment variable that configures what the maintenance mode,
+// which runs on schedule and workflow_dispatch events, does with expired CommitGuard tags:
+// retireDelete or retireArchive. When unset, expired tags are only listed.
+const retireGuardsEnv = "RETIRE_GUARDS"
+
+// Constant block for the values of retireGuardsEnv.
+const (
+	// retireDelete deletes expired CommitGuard tags.
+	retireDelete = "delete"
+
+	// retireArchive renames expired CommitGuard tags to start with archiveTagPrefix, which
+	// keeps the history of why commits were guarded while no longer listing them as guards.
+	retireArchive = "archive"
+)
+
+// archiveTagPrefix is prepended to the name of archived CommitGuard tags, e.g.
+// archived/commitguard-1760659200.
+const archiveTagPrefix = "archived/"
+
+// retireMode returns the configured way expired CommitGuard tags are retired, or an empty
+// string if they are only listed.
+func retireMode() (string, error) {
+	switch mode := strings.TrimSpace(os.Getenv(retireGuardsEnv)); mode {
+	case "", retireDelete, retireArchive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%s must be one of %q or %q, got %q", retireGuardsEnv, retireDelete, retireArchive, mode)
+	}
+}
+
+// runMaintenance retires every expired CommitGuard tag of the repository the action is
+// running in.
+func runMaintenance(ctx context.Context, client *github.Client, actionCtx *actions.GitHubContext) error {
+	org, repo, ok := strings.Cut(actionCtx.Repository, "/")
+	if !ok {
+		return fmt.Errorf("unable to determine repository from %q", actionCtx.Repository)
+	}
+
+	mode, err := retireMode()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	guards, err := listGuards(ctx, client, org, repo, now, true)
+	if err != nil {
+		return errors.Wrap(err, "list CommitGuard tags")
+	}
+
+	// Tags that can't be read or retired are reported once every other tag was handled, so
+	// that a single broken tag doesn't block retiring the rest.
+	var failures []string
+
+	var expired []*guard
+	for _, g := range guards {
+		// The expiry can be set in the annotation, and archiving needs the tag object.
+		if err := g.fetchAnnotation(ctx, client, org, repo); err != nil {
+			actions.Warningf("unable to read CommitGuard tag %q: %v", g.Tag, err)
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		if g.expired(now) {
+			expired = append(expired, g)
+		}
+	}
+
+	var retired []*guard
+	for _, g := range expired {
+		if err := retireGuard(ctx, client, org, repo, mode, g); err != nil {
+			actions.Warningf("unable to retire CommitGuard tag %q: %v", g.Tag, err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		retired = append(retired, g)
+	}
+
+	if len(retired) > 0 {
+		gh.AddStepSummary(retiredGuardsMarkdown(mode, retired))
+	} else if len(failures) == 0 {
+		actions.Infof("no expired CommitGuard tags found")
+	}
+
+	if len(failures) > 0 {
+		return errors.Errorf("unable to maintain %d CommitGuard tag(s):\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// retireGuard deletes or archives the tag of an expired guard depending on mode, or only
+// logs it when mode is empty.
+func retireGuard(ctx context.Context, client *github.Client, org, repo, mode string, g *guard) error {
+	switch mode {
+	case retireArchive:
+		if err := archiveGuard(ctx, client, org, repo, g); err != nil {
+			return err
+		}
+		fallthrough
+	case retireDelete:
+		if _, err := client.Git.DeleteRef(ctx, org, repo, "tags/"+g.Tag); err != nil {
+			return errors.Wrapf(err, "delete CommitGuard tag %q", g.Tag)
+		}
+		actions.Infof("retired CommitGuard tag %q (%s), it expired on %s", g.Tag, mode, g.Expires.Format(time.RFC3339))
+	default:
+		actions.Infof("CommitGuard tag %q expired on %s, set %s to retire it", g.Tag, g.Expires.Format(time.RFC3339), retireGuardsEnv)
+	}
+	return nil
+}
+
+// archiveGuard creates the archived tag of the guard, see archiveTagPrefix. An archived tag
+// that already points to the same object, e.g. because deleting the original tag failed on
+// a previous run, is kept as is.
+func archiveGuard(ctx context.Context, client *github.Client, org, repo string, g *guard) error {
+	archived := archiveTagPrefix + g.Tag
+
+	_, res, err := client.Git.CreateRef(ctx, org, repo, github.CreateRef{
+		Ref: "refs/tags/" + archived,
+		SHA: g.ObjectSHA,
+	})
+	if err == nil {
+		return nil
+	}
+	if res == nil || res.StatusCode != http.StatusUnprocessableEntity {
+		return errors.Wrapf(err, "archive CommitGuard tag %q", g.Tag)
+	}
+
+	// The archived tag already exists.
+	ref, _, getErr := client.Git.GetRef(ctx, org, repo, "tags/"+archived)
+	if getErr != nil {
+		return errors.Wrapf(err, "archive CommitGuard tag %q", g.Tag)
+	}
+	if ref.GetObject().GetSHA() != g.ObjectSHA {
+		return errors.Errorf("archive CommitGuard tag %q: tag %q already exists and points to %s", g.Tag, archived,
+			ref.GetObject().GetSHA())
+	}
+
+	actions.Infof("CommitGuard tag %q was already archived as %q", g.Tag, archived)
+	return nil
+}
+
+// retiredGuardsMarkdown renders the expired guards and what was done with them as markdown
+// for the job summary.
+func retiredGuardsMarkdown(mode string, expired []*guard) string {
+	action := "Expired"
+	switch mode {
+	case retireDelete:
+		action = "Deleted"
+	case retireArchive:
+		action = "Archived"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s CommitGuard tags\n\n", action)
+	fmt.Fprintln(&b, "| Tag | Commit | Expired |")
+	fmt.Fprintln(&b, "|---|---|---|")
+	for _, g := range expired {
+		tag := "`" + g.Tag + "`"
+		if mode == retireArchive {
+			tag = fmt.Sprintf("`%s` → `%s%s`", g.Tag, archiveTagPrefix, g.Tag)
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s |\n", tag, g.SHA, g.Expires.UTC().Format(time.DateOnly))
+	}
+
+	if mode == "" {
+		fmt.Fprintf(&b, "\nSet `%s` to `%s` or `%s` to retire these tags.\n", retireGuardsEnv, retireDelete, retireArchive)
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the reporting of missing required commits through the
+// error, the job summary and, optionally, a comment on the pull request.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// commentMarker is included in the body of the comment this action creates so that it can
+// be found, updated and deleted by later runs.
+const commentMarker = "<!-- commitguard -->"
+
+// reportMissingGuards reports the guards whose required commits are missing from the pull
+// request in the job summary and, if enabled, in a comment on the pull request, and returns
+// the error failing the check, if any.
+func reportMissingGuards(ctx context.Context, client *github.Client, pr *gh.PullRequest, missing []*guard) error {
+	if len(missing) > 0 {
+		gh.AddStepSummary(missingGuardsMarkdown(pr.Base.Ref, missing))
+	}
+
+	if gh.CommentOnFailure() {
+		updateFailureComment(ctx, client, pr, missing)
+	}
+
+	return missingGuardsError(pr.Base.Ref, missing)
+}
+
+// updateFailureComment creates, updates or deletes the comment on the pull request
+// depending on whether required commits are missing or not. Errors are logged as warnings
+// rather than returned so they never mask the result of the check.
+func updateFailureComment(ctx context.Context, client *github.Client, pr *gh.PullRequest, missing []*guard) {
+	var body string
+	if len(missing) > 0 {
+		body = commentMarker + "\n" + missingGuardsMarkdown(pr.Base.Ref, missing) +
+			"\n_This comment will be removed automatically once the branch contains the required commits._\n"
+	}
+
+	err := gh.UpdateIssueComment(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, commentMarker, body)
+	if err != nil {
+		actions.Warningf("unable to update CommitGuard comment: %v", err)
+	}
+}
+
+// missingGuardsError returns an error naming every guard whose required commit is missing
+// from a branch based on baseBranch, along with why it is required, or nil if none are
+// missing.
+func missingGuardsError(baseBranch string, missing []*guard) error {
+	if len(missing) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(missing))
+	for _, g := range missing {
+		line := fmt.Sprintf("- %s (%s)", g.SHA, g.Tag)
+		if tagged := g.tagged(); tagged != "" {
+			line = fmt.Sprintf("- %s (%s, %s)", g.SHA, g.Tag, tagged)
+		}
+
+		if g.Message != "" {
+			line += "\n  " + strings.ReplaceAll(g.Message, "\n", "\n  ")
+		}
+		lines = append(lines, line)
+	}
+
+	return fmt.Errorf("branch does not contain %d required commit(s), please rebase onto %s:\n%s",
+		len(missing), baseBranch, strings.Join(lines, "\n"))
+}
+
+// missingGuardsMarkdown renders the guards whose required commits are missing from a branch
+// based on baseBranch as markdown, for the job summary and the pull request comment.
+func missingGuardsMarkdown(baseBranch string, missing []*guard) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "### :x: Branch is missing required commits")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "Pull requests targeting `%s` must contain %d commit(s) that this branch does not, please rebase onto `%s`.\n",
+		baseBranch, len(missing), baseBranch)
+
+	for _, g := range missing {
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "#### `%s` (`%s`)\n", g.Tag, g.SHA)
+
+		if tagged := g.tagged(); tagged != "" {
+			fmt.Fprintln(&b)
+			fmt.Fprintf(&b, "_%s._\n", upperFirst(tagged))
+		}
+
+		if g.Message != "" {
+			fmt.Fprintln(&b)
+			fmt.Fprintf(&b, "> %s\n", strings.ReplaceAll(g.Message, "\n", "\n> "))
+		}
+	}
+
+	return b.String()
+}
+
+// tagged returns who created the tag of the guard and when, e.g. "tagged by Jane Doe on
+// 2026-10-17", or an empty string for lightweight tags.
+func (g *guard) tagged() string {
+	var parts []string
+	if g.Tagger != "" {
+		parts = append(parts, "by "+g.Tagger)
+	}
+
+	if !g.Date.IsZero() {
+		parts = append(parts, "on "+g.Date.UTC().Format("2006-01-02"))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return "tagged " + strings.Join(parts, " ")
+}
+
+// upperFirst returns s with its first letter upper cased.
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
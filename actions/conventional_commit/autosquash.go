@@ -0,0 +1,86 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the check that rejects pull requests with commits that
+// are meant to be squashed or reworded before merging, e.g. fixup! or WIP commits.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// Constant block for the environment variables that configure the autosquash check.
+const (
+	// rejectAutosquashCommitsEnv is the environment variable that, when set to "true",
+	// fails pull requests that have commits created by git commit --fixup or --squash, or
+	// commits matching wipPatternEnv. These slip onto the base branch with rebase merges.
+	rejectAutosquashCommitsEnv = "REJECT_AUTOSQUASH_COMMITS"
+
+	// wipPatternEnv is the environment variable containing the regular expression that the
+	// subject of work in progress commits matches. Defaults to defaultWIPPattern.
+	wipPatternEnv = "WIP_PATTERN"
+)
+
+// defaultWIPPattern matches subjects like "WIP", "wip: tests" or "[WIP] add picker".
+const defaultWIPPattern = `(?i)^(?:wip\b|\[wip\])`
+
+// autosquashPrefixes are the prefixes git adds to the subject of commits created by git
+// commit --fixup or --squash, which git rebase --autosquash squashes into their target.
+var autosquashPrefixes = []string{"fixup! ", "squash! ", "amend! "}
+
+// rejectAutosquashCommits returns true if the autosquash check is enabled.
+func rejectAutosquashCommits() bool {
+	return strings.TrimSpace(os.Getenv(rejectAutosquashCommitsEnv)) == "true"
+}
+
+// wipPattern returns the compiled regular expression that work in progress commit subjects
+// match.
+func wipPattern() (*regexp.Regexp, error) {
+	pattern := os.Getenv(wipPatternEnv)
+	if strings.TrimSpace(pattern) == "" {
+		pattern = defaultWIPPattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compile %s", wipPatternEnv)
+	}
+	return re, nil
+}
+
+// validateAutosquashCommits returns an error naming every commit that has to be squashed
+// or reworded before the pull request into base is merged, or nil if there are none.
+func validateAutosquashCommits(commits []*github.RepositoryCommit, wip *regexp.Regexp, base string) error {
+	var offending []string
+	for _, commit := range commits {
+		subject := commitSubject(commit.GetCommit().GetMessage())
+		if isAutosquashSubject(subject) || wip.MatchString(subject) {
+			offending = append(offending, fmt.Sprintf("- %s %q", commit.GetSHA(), subject))
+		}
+	}
+
+	if len(offending) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d commit(s) must be squashed or reworded before merging, "+
+		"run `git rebase --interactive --autosquash origin/%s` and force push:\n%s",
+		len(offending), base, strings.Join(offending, "\n"))
+}
+
+// isAutosquashSubject returns true if subject is the subject of a commit created by git
+// commit --fixup or --squash.
+func isAutosquashSubject(subject string) bool {
+	for _, prefix := range autosquashPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
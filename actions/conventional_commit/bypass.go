@@ -0,0 +1,262 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the bypass rules that apply to a whole pull request,
+// as opposed to individual commits (see commitBypassReason).
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// Constant block for the environment variables that configure bypass rules. Each of them
+// is a space separated list.
+const (
+	// bypassLoginsEnv is the list of GitHub logins (users, or apps/bots like
+	// "renovate[bot]") whose pull requests, and signed commits, bypass the check.
+	bypassLoginsEnv = "BYPASS_LOGINS"
+
+	// bypassLabelsEnv is the list of labels that bypass the check when applied to a pull
+	// request by one of the repository's code owners.
+	bypassLabelsEnv = "BYPASS_LABELS"
+
+	// bypassTeamsEnv is the list of teams, formatted as "org/team-slug", whose members'
+	// pull requests bypass the check. Reading team membership requires org-wide access,
+	// so PAT_OUTREACH_CI should be provided when this is used.
+	bypassTeamsEnv = "BYPASS_TEAMS"
+)
+
+// codeOwnersPaths are the locations GitHub looks for a CODEOWNERS file, in order.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// bypassLogins returns the set of logins read from bypassLoginsEnv.
+func bypassLogins() map[string]struct{} {
+	return envSet(bypassLoginsEnv)
+}
+
+// envSet returns the space separated values of the given environment variable as a set.
+func envSet(name string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, value := range strings.Fields(os.Getenv(name)) {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+// newTeamClient returns the client used to read team memberships for bypassTeamsEnv, which
+// requires org-wide access. The repository client is returned when no teams are configured,
+// or when no org-wide client can be created. It is created once per run and passed to
+// pullRequestBypassReason.
+func newTeamClient(ctx context.Context, client *github.Client) *github.Client {
+	if len(strings.Fields(os.Getenv(bypassTeamsEnv))) == 0 {
+		return client
+	}
+
+	teamClient, err := gh.NewClient(ctx, true)
+	if err != nil {
+		actions.Warningf("unable to create org-wide client for team membership checks, using repository client: %v", err)
+		return client
+	}
+	return teamClient
+}
+
+// pullRequestBypassReason returns why the pull request is allowed to bypass the
+// conventional commit check, or an empty string if it is not allowed to. The author's
+// login, the author's team membership and the pull request's labels are checked, in that
+// order. Team memberships of the author are read with teamClient, see newTeamClient.
+func pullRequestBypassReason(ctx context.Context, client, teamClient *github.Client, pr *gh.PullRequest) (string, error) {
+	author := pr.User.Login
+	if _, ok := bypassLogins()[author]; ok && author != "" {
+		return fmt.Sprintf("pull request author %q is in %s", author, bypassLoginsEnv), nil
+	}
+
+	for _, team := range strings.Fields(os.Getenv(bypassTeamsEnv)) {
+		member, err := isTeamMember(ctx, teamClient, team, author)
+		if err != nil {
+			return "", err
+		}
+		if member {
+			return fmt.Sprintf("pull request author %q is a member of team %q", author, team), nil
+		}
+	}
+
+	return labelBypassReason(ctx, client, teamClient, pr)
+}
+
+// labelBypassReason returns why the pull request is allowed to bypass the check because of
+// a label in bypassLabelsEnv, or an empty string if it is not allowed to. Labels only count
+// when the user who most recently applied them is a code owner of the repository. Team
+// memberships of code owner teams are read with teamClient, see newTeamClient.
+func labelBypassReason(ctx context.Context, client, teamClient *github.Client, pr *gh.PullRequest) (string, error) {
+	bypassLabels := envSet(bypassLabelsEnv)
+
+	var labels []string
+	for i := range pr.Labels {
+		if _, ok := bypassLabels[pr.Labels[i].Name]; ok {
+			labels = append(labels, pr.Labels[i].Name)
+		}
+	}
+
+	if len(labels) == 0 {
+		return "", nil
+	}
+
+	org, repo := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name
+	owners, err := codeOwners(ctx, client, org, repo, pr.Base.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	for _, label := range labels {
+		labeler, err := lastLabeler(ctx, client, org, repo, pr.Number, label)
+		if err != nil {
+			return "", err
+		}
+
+		owner, err := isCodeOwner(ctx, teamClient, owners, labeler)
+		if err != nil {
+			return "", err
+		}
+
+		if !owner {
+			actions.Warningf("label %q was applied by %q who is not a code owner, not bypassing check", label, labeler)
+			continue
+		}
+
+		return fmt.Sprintf("label %q was applied by code owner %q", label, labeler), nil
+	}
+
+	return "", nil
+}
+
+// lastLabeler returns the login of the user who most recently applied label to the given
+// issue or pull request.
+func lastLabeler(ctx context.Context, client *github.Client, org, repo string, number int, label string) (string, error) {
+	eventPage := 1
+	eventsPerPage := 100
+
+	var labeler string
+	for eventPage != 0 {
+		events, res, err := client.Issues.ListIssueEvents(ctx, org, repo, number, &github.ListOptions{
+			Page:    eventPage,
+			PerPage: eventsPerPage,
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "list events on #%d", number)
+		}
+
+		// Events are returned oldest first, so the last match wins.
+		for _, event := range events {
+			if event.GetEvent() == "labeled" && event.GetLabel().GetName() == label {
+				labeler = event.GetActor().GetLogin()
+			}
+		}
+
+		eventPage = res.NextPage
+	}
+
+	return labeler, nil
+}
+
+// codeOwners returns every owner ("@user" or "@org/team") listed in the repository's
+// CODEOWNERS file at ref.
+func codeOwners(ctx context.Context, client *github.Client, org, repo, ref string) ([]string, error) {
+	for _, path := range codeOwnersPaths {
+		b, err := gh.GetFileContents(ctx, client, org, repo, path, ref)
+		if errors.Is(err, gh.ErrFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read CODEOWNERS")
+		}
+
+		return parseCodeOwners(string(b)), nil
+	}
+
+	actions.Warningf("no CODEOWNERS file found, label bypass is not possible")
+	return nil, nil
+}
+
+// parseCodeOwners returns the de-duplicated owners listed in the contents of a CODEOWNERS
+// file, without the leading "@". Email owners are ignored.
+func parseCodeOwners(contents string) []string {
+	seen := make(map[string]struct{})
+	var owners []string
+	for _, line := range strings.Split(contents, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		for _, owner := range fields[1:] {
+			if !strings.HasPrefix(owner, "@") {
+				continue
+			}
+
+			owner = strings.TrimPrefix(owner, "@")
+			if _, ok := seen[owner]; !ok {
+				seen[owner] = struct{}{}
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+// isCodeOwner returns true if login is one of the owners, or a member of one of the teams
+// in owners.
+func isCodeOwner(ctx context.Context, client *github.Client, owners []string, login string) (bool, error) {
+	if login == "" {
+		return false, nil
+	}
+
+	for _, owner := range owners {
+		if !strings.Contains(owner, "/") {
+			if strings.EqualFold(owner, login) {
+				return true, nil
+			}
+			continue
+		}
+
+		member, err := isTeamMember(ctx, client, owner, login)
+		if err != nil {
+			return false, err
+		}
+		if member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isTeamMember returns true if login is an active member of team, formatted as
+// "org/team-slug".
+func isTeamMember(ctx context.Context, client *github.Client, team, login string) (bool, error) {
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok {
+		return false, fmt.Errorf("team %q must be formatted as org/team-slug", team)
+	}
+
+	membership, res, err := client.Teams.GetTeamMembershipBySlug(ctx, org, slug, login)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "get membership of %q in team %q", login, team)
+	}
+
+	return membership.GetState() == "active", nil
+}
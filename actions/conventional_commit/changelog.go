@@ -0,0 +1,58 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for previewing the changelog entries a pull
+// request will produce once merged.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/getoutreach/actions/pkg/gh"
+)
+
+// changelogPreview renders the changelog entries the pull request will produce once merged
+// as markdown for the job summary. When commits were validated, every commit produces an
+// entry as they all end up on the base branch when rebase merging, otherwise the title and
+// description do.
+func changelogPreview(pr *gh.PullRequest, rep *report) string {
+	link := &conventional.Link{Text: fmt.Sprintf("#%d", pr.Number), URL: pr.HTMLURL}
+
+	commits := []*conventional.Commit{rep.Commit}
+	if rep.Commits != nil {
+		commits = commits[:0]
+		for _, commit := range rep.parsedCommits() {
+			commits = append(commits, commit.Commit)
+		}
+	}
+
+	var changelog conventional.Changelog
+	var types []string
+	for _, commit := range commits {
+		changelog.Add(commit, link)
+		if !slices.Contains(types, "`"+commit.Type+"`") {
+			types = append(types, "`"+commit.Type+"`")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "## Changelog preview")
+	fmt.Fprintln(&b)
+
+	if changelog.Empty() {
+		fmt.Fprint(&b, "This pull request will not produce a changelog entry")
+		if len(types) > 0 {
+			fmt.Fprintf(&b, ", as %s changes are not included in release notes", strings.Join(types, " and "))
+		}
+		fmt.Fprintln(&b, ".")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Once merged, this pull request will result in a **%s** release with the following release notes:\n\n",
+		semverBump(rep))
+	fmt.Fprint(&b, changelog.Markdown())
+	return b.String()
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for publishing the result of the check as a
+// GitHub check run.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// checkRunNameEnv is the environment variable that, when set, enables publishing the
+// result of the check as a check run with the given name on the head commit of the pull
+// request. Branch protection can then require that named check.
+const checkRunNameEnv = "CHECK_RUN_NAME"
+
+// checkRunAnnotationsPath is the path check run annotations are attached to. GitHub requires
+// annotations to point at a file, but titles and commit messages don't live in one, so they
+// point at the .github directory every repository using this action has.
+const checkRunAnnotationsPath = ".github"
+
+// maxCheckRunAnnotations is the maximum number of annotations GitHub accepts in a single
+// request.
+const maxCheckRunAnnotations = 50
+
+// checkRunName returns the name of the check run to publish, or an empty string if
+// publishing a check run is disabled.
+func checkRunName() string {
+	return strings.TrimSpace(os.Getenv(checkRunNameEnv))
+}
+
+// publishCheckRun creates a completed check run with the given name on headSHA in the given
+// org/repo, summarizing the reports. Multiple reports are passed when a merge group with
+// multiple pull requests was checked.
+func publishCheckRun(ctx context.Context, client *github.Client, org, repo, headSHA, name string, reps ...*report) error {
+	var summary, text strings.Builder
+	for _, rep := range reps {
+		if len(reps) > 1 {
+			fmt.Fprintf(&summary, "### #%d\n\n", rep.Number)
+		}
+		fmt.Fprintln(&summary, rep.markdownTable())
+		fmt.Fprint(&text, rep.markdownFailures())
+	}
+
+	conclusion, title := checkRunResult(reps)
+	return createCheckRun(ctx, client, org, repo, headSHA, name, conclusion, &github.CheckRunOutput{
+		Title:       &title,
+		Summary:     github.Ptr(summary.String()),
+		Text:        github.Ptr(text.String()),
+		Annotations: checkRunAnnotations(reps),
+	})
+}
+
+// checkRunAnnotations returns an annotation for every failed title and commit in the
+// reports, so that they are listed on the pull request's checks tab. Failures of reports
+// that only warn are annotated as warnings.
+func checkRunAnnotations(reps []*report) []*github.CheckRunAnnotation {
+	var annotations []*github.CheckRunAnnotation
+	for _, rep := range reps {
+		level := "failure"
+		if rep.WarnOnly {
+			level = "warning"
+		}
+
+		if rep.TitleErr != nil {
+			annotations = append(annotations, newCheckRunAnnotation(level,
+				fmt.Sprintf("Title of #%d: %s", rep.Number, rep.Title), rep.TitleErr))
+		}
+
+		for i := range rep.Commits {
+			if c := &rep.Commits[i]; c.Err != nil {
+				annotations = append(annotations, newCheckRunAnnotation(level,
+					fmt.Sprintf("Commit %s: %s", shortSHA(c.SHA), c.Subject), c.Err))
+			}
+		}
+	}
+
+	if len(annotations) > maxCheckRunAnnotations {
+		// Every failure is still listed in the text of the check run.
+		annotations = annotations[:maxCheckRunAnnotations]
+	}
+	return annotations
+}
+
+// newCheckRunAnnotation returns an annotation with the given level and title for err.
+func newCheckRunAnnotation(level, title string, err error) *github.CheckRunAnnotation {
+	return &github.CheckRunAnnotation{
+		Path:            github.Ptr(checkRunAnnotationsPath),
+		StartLine:       github.Ptr(1),
+		EndLine:         github.Ptr(1),
+		AnnotationLevel: &level,
+		Title:           &title,
+		Message:         github.Ptr(err.Error()),
+	}
+}
+
+// publishErrorCheckRun creates a failed check run with the given name on headSHA in the
+// given org/repo for a check that could not be completed because of checkErr, so that a
+// required check run is never left missing.
+func publishErrorCheckRun(ctx context.Context, client *github.Client, org, repo, headSHA, name string, checkErr error) error {
+	return createCheckRun(ctx, client, org, repo, headSHA, name, "failure", &github.CheckRunOutput{
+		Title:   github.Ptr("Conventional commit check could not be completed"),
+		Summary: github.Ptr(checkErr.Error()),
+	})
+}
+
+// checkRunResult returns the conclusion and title of the check run summarizing the reports.
+func checkRunResult(reps []*report) (conclusion, title string) {
+	var failures, bypassed int
+	warnOnly := true
+	for _, rep := range reps {
+		failures += rep.failures()
+		warnOnly = warnOnly && rep.WarnOnly
+		if rep.Bypass != "" {
+			bypassed++
+		}
+	}
+
+	switch {
+	case failures > 0 && warnOnly:
+		// Draft pull requests that are only warned about must not show up as failed.
+		return "neutral", fmt.Sprintf("%d conventional commit problem(s) found", failures)
+	case failures > 0:
+		return "failure", fmt.Sprintf("%d conventional commit problem(s) found", failures)
+	case len(reps) == 1 && bypassed == 1:
+		return "success", "Pull request bypassed the conventional commit check: " + reps[0].Bypass
+	case len(reps) > 0 && bypassed == len(reps):
+		return "success", "Pull requests bypassed the conventional commit check"
+	default:
+		return "success", "Pull request follows the conventional commit format"
+	}
+}
+
+// createCheckRun creates a completed check run with the given name, conclusion and output
+// on headSHA in the given org/repo.
+func createCheckRun(ctx context.Context, client *github.Client, org, repo, headSHA, name, conclusion string,
+	output *github.CheckRunOutput) error {
+	checkRun, _, err := client.Checks.CreateCheckRun(ctx, org, repo, github.CreateCheckRunOptions{
+		Name:        name,
+		HeadSHA:     headSHA,
+		Status:      github.Ptr("completed"),
+		Conclusion:  &conclusion,
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output:      output,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "create check run %q", name)
+	}
+
+	actions.Infof("published check run %q: %s", name, checkRun.GetHTMLURL())
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for commenting on pull requests whose title
+// failed validation.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// commentMarker is included in the body of the comment this action creates so that it can
+// be found, updated and deleted by later runs.
+const commentMarker = "<!-- conventional_commit -->"
+
+// updateFailureComment creates, updates or deletes the comment on the pull request
+// depending on whether the title failed validation (titleErr != nil) or not. Errors are
+// logged as warnings rather than returned so they never mask the result of the check.
+func updateFailureComment(ctx context.Context, client *github.Client, pr *gh.PullRequest, cfg *config, titleErr error) {
+	var body string
+	if titleErr != nil {
+		body = renderFailureComment(cfg, pr.Title, titleErr)
+	}
+
+	err := gh.UpdateIssueComment(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, commentMarker, body)
+	if err != nil {
+		actions.Warningf("unable to update conventional commit comment: %v", err)
+	}
+}
+
+// renderFailureComment renders the markdown body of the comment explaining why title failed
+// validation with titleErr.
+func renderFailureComment(cfg *config, title string, titleErr error) string {
+	diagnosis := explainTitleError(cfg, title, titleErr)
+
+	types := make([]string, 0, len(cfg.AllowedTypes()))
+	for cType := range cfg.AllowedTypes() {
+		types = append(types, "`"+cType+"`")
+	}
+	sort.Strings(types)
+
+	var b strings.Builder
+	fmt.Fprintln(&b, commentMarker)
+	fmt.Fprintln(&b, "### :x: Pull request title failed the conventional commit check")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "The title `%s` failed validation: %s.\n\n", title, titleErr.Error())
+	if len(diagnosis.Problems) > 0 {
+		fmt.Fprintln(&b, "What's wrong:")
+		fmt.Fprintln(&b)
+		for _, problem := range diagnosis.Problems {
+			fmt.Fprintf(&b, "- %s\n", problem)
+		}
+		fmt.Fprintln(&b)
+	}
+	if diagnosis.Suggestion != "" {
+		fmt.Fprintln(&b, "Suggested title:")
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "```")
+		fmt.Fprintln(&b, diagnosis.Suggestion)
+		fmt.Fprintln(&b, "```")
+		fmt.Fprintln(&b)
+	}
+	fmt.Fprintf(&b, "Allowed types: %s. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.\n",
+		strings.Join(types, ", "))
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "_This comment will be removed automatically once the title is fixed._")
+
+	return b.String()
+}
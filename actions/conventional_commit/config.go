@@ -0,0 +1,117 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the configuration that repositories can provide to
+// customize the conventional commit check.
+
+package main
+
+import (
+	"context"
+
+	"github.com/getoutreach/actions/pkg/commitlint"
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath is the path, relative to the root of the repository being checked,
+// that the configuration file is read from. This can be overridden with the CONFIG_PATH
+// environment variable.
+//
+// An example configuration file looks like:
+//
+//	types:
+//	  add: [deps, release, security]
+//	  remove: [style]
+//	gitmoji: true
+//	scopes:
+//	  required: true
+//	  pattern: '[-\w./]+'
+//	  enforce_paths: true
+//	  multiple_areas: either
+//	  allowed:
+//	    - name: api
+//	      paths: ["services/api/**"]
+//	    - name: web
+//	      paths: ["services/web/**"]
+//	    - name: deps
+//	labels:
+//	  types:
+//	    feat: feature
+//	    fix: bugfix
+//	  breaking: breaking-change
+//	references:
+//	  types: [feat, fix]
+//	  jira_projects: [DT]
+//	lint:
+//	  max_header_length: 72
+//	  lowercase: true
+//	  no_trailing_period: true
+//	  banned_words: [WIP]
+//	  imperative: true
+const defaultConfigPath = ".github/conventional_commit.yaml"
+
+// config is the repository level configuration for the conventional commit check. The
+// zero value of this type is the default configuration.
+type config struct {
+	// Config configures how commit messages are validated. It is shared with the
+	// commit-msg hook, see runHook.
+	commitlint.Config `yaml:",inline"`
+
+	// Labels configures the labels applied to pull requests when labeling is enabled.
+	Labels labelsConfig `yaml:"labels"`
+}
+
+// labelsConfig configures which labels are applied to a pull request based on its parsed
+// title. When a field is empty, the corresponding default is used.
+type labelsConfig struct {
+	// Types maps commit types to the label that is applied for them. Defaults to
+	// defaultTypeLabels.
+	Types map[string]string `yaml:"types"`
+
+	// Scopes maps commit scopes to the label that is applied for them.
+	Scopes map[string]string `yaml:"scopes"`
+
+	// Breaking is the label applied to breaking changes. Defaults to
+	// defaultBreakingLabel.
+	Breaking string `yaml:"breaking"`
+}
+
+// loadConfig reads the configuration file from the given org/repo at ref. The default
+// configuration is returned if the file does not exist.
+func loadConfig(ctx context.Context, client *github.Client, org, repo, ref string) (*config, error) {
+	path := configPath()
+
+	b, err := gh.GetFileContents(ctx, client, org, repo, path, ref)
+	if err != nil {
+		if errors.Is(err, gh.ErrFileNotFound) {
+			actions.Infof("no configuration file found at %q, using defaults", path)
+			return &config{}, nil
+		}
+		return nil, errors.Wrap(err, "read configuration file")
+	}
+
+	cfg, err := parseConfig(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse configuration file %q", path)
+	}
+
+	actions.Infof("loaded configuration file %q", path)
+	return cfg, nil
+}
+
+// parseConfig parses and validates a configuration file.
+func parseConfig(b []byte) (*config, error) {
+	var cfg config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Compile(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
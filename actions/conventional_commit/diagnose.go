@@ -0,0 +1,304 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for explaining why a title is not a valid
+// conventional commit and suggesting a corrected one.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/commitlint"
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+)
+
+// typePlaceholder is used in suggested titles when the type cannot be guessed.
+const typePlaceholder = "<type>"
+
+// formatProblem is the problem reported when a title can't be explained any further.
+const formatProblem = "the title must be formatted as `type(scope)!: description`, where the scope and `!` are optional"
+
+// commonTypeMistakes maps commonly used, but not allowed, types to the allowed type that
+// was most likely meant.
+var commonTypeMistakes = map[string]string{
+	"feature":       "feat",
+	"features":      "feat",
+	"bug":           "fix",
+	"bugfix":        "fix",
+	"hotfix":        "fix",
+	"fixes":         "fix",
+	"fixed":         "fix",
+	"doc":           "docs",
+	"documentation": "docs",
+	"tests":         "test",
+	"chores":        "chore",
+	"refactoring":   "refactor",
+	"performance":   "perf",
+	"styles":        "style",
+}
+
+// leadingVerbTypes maps the first word of a title without a type to the type that is most
+// likely appropriate.
+var leadingVerbTypes = map[string]string{
+	"add":       "feat",
+	"adds":      "feat",
+	"added":     "feat",
+	"implement": "feat",
+	"introduce": "feat",
+	"support":   "feat",
+	"fix":       "fix",
+	"fixes":     "fix",
+	"fixed":     "fix",
+	"resolve":   "fix",
+	"doc":       "docs",
+	"docs":      "docs",
+	"document":  "docs",
+	"refactor":  "refactor",
+	"bump":      "chore",
+	"update":    "chore",
+}
+
+// looseHeaderPattern matches anything that resembles a conventional commit header, so that
+// the individual pieces can be inspected to explain what is wrong with it. The %s is
+// replaced with the pattern of what is allowed in front of the type.
+const looseHeaderPattern = `^\s*(?P<prefix>%s)(?P<type>[^\s(:!]*)\s*(?:\((?P<scope>[^)]*)\))?\s*(?P<breaking>!)?\s*(?P<colon>:?)(?P<space>\s*)(?P<description>.*?)\s*$` //nolint:lll // Why: Regular expression.
+
+// Variable block for regular expression parsing.
+var (
+	// reLooseHeader is looseHeaderPattern for configurations that don't allow gitmoji.
+	reLooseHeader = regexp.MustCompile(fmt.Sprintf(looseHeaderPattern, ""))
+
+	// reLooseGitmojiHeader is looseHeaderPattern for configurations that allow a gitmoji
+	// in front of the type, which is kept in suggested titles.
+	reLooseGitmojiHeader = regexp.MustCompile(fmt.Sprintf(looseHeaderPattern, `(?:`+conventional.GitmojiPattern+`\s+)?`))
+)
+
+// looseHeader returns the regular expression that loosely matches headers for cfg, see
+// looseHeaderPattern.
+func looseHeader(cfg *config) *regexp.Regexp {
+	if cfg.Gitmoji {
+		return reLooseGitmojiHeader
+	}
+	return reLooseHeader
+}
+
+// titleDiagnosis explains why a title is not a valid conventional commit.
+type titleDiagnosis struct {
+	// Problems is a human readable list of everything that is wrong with the title.
+	Problems []string
+
+	// Suggestion is a corrected title, or an empty string if none could be made. It may
+	// contain typePlaceholder if the type could not be determined.
+	Suggestion string
+}
+
+// explainTitleError returns the diagnosis of a title that failed validation with titleErr.
+// Only errors about the format, type or scope of the header are diagnosed by diagnoseTitle.
+// Missing references list the accepted formats and invalid reverts what they must
+// reference, without a suggestion since what is referenced can't be guessed. Other errors
+// explain themselves.
+func explainTitleError(cfg *config, title string, titleErr error) *titleDiagnosis {
+	var revertErr *revertError
+	switch {
+	case errors.Is(titleErr, commitlint.ErrInvalidSyntax), errors.Is(titleErr, errInvalidTitleSyntax),
+		errors.Is(titleErr, commitlint.ErrTypeNotAllowed), errors.Is(titleErr, commitlint.ErrScopeNotAllowed):
+		return diagnoseTitle(cfg, title)
+	case errors.Is(titleErr, commitlint.ErrMissingReference):
+		return &titleDiagnosis{Problems: []string{
+			"reference an issue in the scope, the description or the branch name, in one of the accepted formats: " +
+				strings.Join(cfg.References.Formats(), ", "),
+		}}
+	case errors.As(titleErr, &revertErr):
+		return &titleDiagnosis{Problems: []string{
+			"a `revert` must reference the commits or the pull request it reverts in the description, with a " +
+				"`This reverts commit <sha>.` line, a `Refs: <sha>` footer or a `Reverts org/repo#<number>` line",
+			"the referenced commits must exist in this repository and the referenced pull requests must exist",
+		}}
+	default:
+		return &titleDiagnosis{}
+	}
+}
+
+// diagnoseTitle explains why title is not a valid conventional commit according to cfg and
+// suggests a corrected title.
+func diagnoseTitle(cfg *config, title string) *titleDiagnosis {
+	var d titleDiagnosis
+	allowed := cfg.AllowedTypes()
+
+	title = commitSubject(title)
+	re := looseHeader(cfg)
+	m := re.FindStringSubmatch(title)
+	if m == nil {
+		// This can't be explained piece by piece, so only the expected format is given.
+		d.Problems = append(d.Problems, formatProblem)
+		return &d
+	}
+
+	prefix := m[re.SubexpIndex("prefix")]
+	cType := m[re.SubexpIndex("type")]
+	scope := m[re.SubexpIndex("scope")]
+	breaking := m[re.SubexpIndex("breaking")]
+	colon := m[re.SubexpIndex("colon")]
+	description := m[re.SubexpIndex("description")]
+
+	if colon == "" {
+		d.Problems = append(d.Problems, "the type and description must be separated by a colon followed by a space (`: `)")
+
+		if _, ok := lookupType(allowed, cType); !ok && scope == "" && breaking == "" {
+			// This title doesn't appear to have a type at all, so the whole title is the
+			// description.
+			d.Problems = append(d.Problems, "the title must start with a type, e.g. `feat: `")
+			description = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(title), prefix))
+			cType = typePlaceholder
+			if words := strings.Fields(description); len(words) > 0 && leadingVerbTypes[strings.ToLower(words[0])] != "" {
+				cType = leadingVerbTypes[strings.ToLower(words[0])]
+			}
+		}
+	} else if m[re.SubexpIndex("space")] != " " {
+		d.Problems = append(d.Problems, "the colon after the type must be followed by exactly one space")
+	}
+
+	if cType != typePlaceholder {
+		cType = d.diagnoseType(allowed, cType)
+	}
+
+	if scope != "" {
+		scope = d.diagnoseScope(cfg, scope)
+	} else if err := cfg.ValidateScope(""); err != nil {
+		d.Problems = append(d.Problems, err.Error())
+		scope, _ = areaScope(cfg)
+	}
+
+	if description == "" {
+		d.Problems = append(d.Problems, "the description after the colon must not be empty")
+		description = "<description>"
+	}
+
+	if scope != "" {
+		scope = "(" + scope + ")"
+	}
+	d.Suggestion = prefix + cType + scope + breaking + ": " + description
+
+	if len(d.Problems) == 0 {
+		d.Problems = append(d.Problems, formatProblem)
+	}
+
+	return &d
+}
+
+// diagnoseType records any problems with the given type and returns the type that should
+// be used instead.
+func (d *titleDiagnosis) diagnoseType(allowed map[string]struct{}, cType string) string {
+	if cType == "" {
+		d.Problems = append(d.Problems, "the title must start with a type, e.g. `feat: `")
+		return typePlaceholder
+	}
+
+	suggested, ok := lookupType(allowed, cType)
+	switch {
+	case suggested == cType:
+		return cType
+	case ok && strings.EqualFold(suggested, cType):
+		d.Problems = append(d.Problems, fmt.Sprintf("the type `%s` must be lowercase", cType))
+		return suggested
+	case ok:
+		d.Problems = append(d.Problems, fmt.Sprintf("the type `%s` is not allowed, did you mean `%s`?", cType, suggested))
+		return suggested
+	default:
+		d.Problems = append(d.Problems, fmt.Sprintf("the type `%s` is not in the list of allowed types", cType))
+		return typePlaceholder
+	}
+}
+
+// diagnoseScope records any problems with the given scope and returns the scope that should
+// be used instead.
+func (d *titleDiagnosis) diagnoseScope(cfg *config, scope string) string {
+	pattern := cfg.ScopePattern()
+	reScope, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		// The configuration is compiled before it is used, so this can't happen.
+		return scope
+	}
+
+	scopes := commitlint.SplitScopes(scope)
+	for i := range scopes {
+		if reScope.MatchString(scopes[i]) {
+			continue
+		}
+
+		if pattern == conventional.DefaultScopePattern {
+			d.Problems = append(d.Problems, fmt.Sprintf(
+				"the scope `%s` may only contain letters, numbers, `_`, `-` and `/`", scopes[i]))
+		} else {
+			d.Problems = append(d.Problems, fmt.Sprintf("the scope `%s` must match the pattern `%s`", scopes[i], pattern))
+		}
+
+		if fixed := fixScope(reScope, scopes[i]); reScope.MatchString(fixed) {
+			scopes[i] = fixed
+		}
+	}
+	fixed := strings.Join(scopes, ",")
+
+	if err := cfg.ValidateScope(fixed); err != nil {
+		d.Problems = append(d.Problems, err.Error())
+		if area, ok := areaScope(cfg); ok {
+			return area
+		}
+	}
+
+	return fixed
+}
+
+// fixScope returns scope with every run of characters that reScope doesn't allow on their
+// own replaced with a "-", e.g. "pencil lead" becomes "pencil-lead".
+func fixScope(reScope *regexp.Regexp, scope string) string {
+	var b strings.Builder
+	invalid := false
+	for _, r := range scope {
+		if !reScope.MatchString(string(r)) {
+			invalid = true
+			continue
+		}
+
+		if invalid && b.Len() > 0 {
+			b.WriteString("-")
+		}
+		invalid = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// areaScope returns the scope matching the areas of the changed files when the
+// configuration enforces it, and whether or not there is one.
+func areaScope(cfg *config) (string, bool) {
+	areas := cfg.ChangedAreas()
+	if !cfg.Scopes.EnforcePaths || len(areas) == 0 {
+		return "", false
+	}
+
+	if len(areas) > 1 && cfg.Scopes.MultipleAreas == commitlint.MultipleAreasNone {
+		return "", true
+	}
+	return strings.Join(areas, ","), true
+}
+
+// lookupType returns the allowed type that cType most likely refers to, and whether or not
+// one was found.
+func lookupType(allowed map[string]struct{}, cType string) (string, bool) {
+	lower := strings.ToLower(cType)
+	if _, ok := allowed[lower]; ok {
+		return lower, true
+	}
+
+	if mistake, ok := commonTypeMistakes[lower]; ok {
+		if _, ok := allowed[mistake]; ok {
+			return mistake, true
+		}
+	}
+
+	return "", false
+}
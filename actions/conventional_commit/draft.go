@@ -0,0 +1,54 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the policy for checking draft pull requests, whose titles
+// are often placeholders until they are ready for review.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+)
+
+// draftPolicyEnv is the environment variable that configures how draft pull requests are
+// checked, one of draftPolicyEnforce (default), draftPolicyWarn or draftPolicySkip.
+const draftPolicyEnv = "DRAFT_POLICY"
+
+// Constant block for the values of draftPolicyEnv.
+const (
+	// draftPolicyEnforce checks draft pull requests like any other pull request.
+	draftPolicyEnforce = "enforce"
+
+	// draftPolicyWarn checks draft pull requests but reports failures as warnings instead
+	// of failing the check.
+	draftPolicyWarn = "warn"
+
+	// draftPolicySkip does not check draft pull requests at all.
+	draftPolicySkip = "skip"
+)
+
+// draftPolicy returns the configured policy for draft pull requests.
+func draftPolicy() (string, error) {
+	switch policy := strings.TrimSpace(os.Getenv(draftPolicyEnv)); policy {
+	case "":
+		return draftPolicyEnforce, nil
+	case draftPolicyEnforce, draftPolicyWarn, draftPolicySkip:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%s must be one of %q, %q or %q, got %q",
+			draftPolicyEnv, draftPolicyEnforce, draftPolicyWarn, draftPolicySkip, policy)
+	}
+}
+
+// pullRequestPolicy returns the policy that applies to the pull request of the event given
+// the configured draft policy. Pull requests that are not drafts, or that the event just
+// marked as ready for review, are always enforced.
+func pullRequestPolicy(pr *gh.PullRequest, event *gh.PullRequestEvent, policy string) string {
+	if !pr.Draft || event.Action == "ready_for_review" {
+		return draftPolicyEnforce
+	}
+	return policy
+}
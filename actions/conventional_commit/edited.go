@@ -0,0 +1,94 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for handling pull_request edited events, which
+// are sent when the title, description or base branch of a pull request changes.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// editSkipReason returns why the check could be skipped for the event, or an empty string if
+// it has to run. Only edited events that changed neither the title, the description (which
+// is parsed for footers) nor the base branch (which the configuration is read from) can be
+// skipped, since they can't change the result of the check.
+func editSkipReason(event *gh.PullRequestEvent) string {
+	if event.Action != "edited" {
+		return ""
+	}
+
+	if event.Changes.Title == nil && event.Changes.Body == nil && event.Changes.Base == nil {
+		return "the title, description and base branch were not edited"
+	}
+	return ""
+}
+
+// skipCheckReason returns why the check can be skipped for the event, or an empty string if
+// it has to run. On top of editSkipReason, which only looks at the event and must not be
+// used to skip the check on its own, the previous check run of the head commit must have
+// passed: a skipped check succeeds, so skipping after a failed check would turn it green
+// without the pull request being fixed.
+func skipCheckReason(ctx context.Context, client *github.Client, event *gh.PullRequestEvent, pr *gh.PullRequest) string {
+	reason := editSkipReason(event)
+	if reason == "" {
+		return ""
+	}
+
+	name := checkRunName()
+	if name == "" {
+		actions.Infof("not skipping conventional commit check, %s is not set so the previous result is unknown", checkRunNameEnv)
+		return ""
+	}
+
+	org, repo := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name
+	runs, _, err := client.Checks.ListCheckRunsForRef(ctx, org, repo, pr.Head.SHA, &github.ListCheckRunsOptions{
+		CheckName: &name,
+		Filter:    github.Ptr("latest"),
+	})
+	if err != nil {
+		actions.Warningf("not skipping conventional commit check, unable to list check runs: %v", err)
+		return ""
+	}
+
+	if len(runs.CheckRuns) == 0 {
+		actions.Infof("not skipping conventional commit check, there is no previous check run %q", name)
+		return ""
+	}
+
+	if conclusion := runs.CheckRuns[0].GetConclusion(); conclusion != "success" && conclusion != "neutral" {
+		actions.Infof("not skipping conventional commit check, the previous check run concluded %q", conclusion)
+		return ""
+	}
+	return reason
+}
+
+// previousTitle returns the title of the pull request before the event edited it, or an
+// empty string if the event did not edit the title.
+func previousTitle(event *gh.PullRequestEvent) string {
+	if event.Action != "edited" || event.Changes.Title == nil {
+		return ""
+	}
+	return event.Changes.Title.From
+}
+
+// titleFixedSummary renders the job summary for a pull request whose title was edited from
+// previous, which failed validation with previousErr, to the valid current title.
+func titleFixedSummary(previous, current string, previousErr error) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "## :white_check_mark: Title fixed")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "The pull request title is now a valid conventional commit.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "| | Title | Result |")
+	fmt.Fprintln(&b, "|---|---|---|")
+	fmt.Fprintf(&b, "| Before | %s | :x: %s |\n", markdownEscape(previous), markdownEscape(previousErr.Error()))
+	fmt.Fprintf(&b, "| After | %s | :white_check_mark: |\n", markdownEscape(current))
+	return b.String()
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the commit-msg git hook mode of the binary, which applies
+// the same validation locally that the action applies in CI.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// hookCommand is the first argument that runs the binary as a commit-msg git hook instead
+// of as a GitHub action. It can be installed with:
+//
+//	go install github.com/getoutreach/actions/actions/conventional_commit@main
+//	printf '#!/bin/sh\nexec conventional_commit commit-msg "$1"\n' > .git/hooks/commit-msg
+//	chmod +x .git/hooks/commit-msg
+const hookCommand = "commit-msg"
+
+// gitScissors is the line below which git ignores everything in a commit message when
+// committing with --verbose or --cleanup=scissors.
+const gitScissors = "# ------------------------ >8 ------------------------"
+
+// hookSkipPrefixes are the prefixes of commit messages that are not checked by the hook,
+// since they are created by git and never end up on the default branch as is.
+var hookSkipPrefixes = append([]string{"Merge "}, autosquashPrefixes...)
+
+// runHook validates the commit message in the file passed as the only positional argument,
+// or read from stdin when there is none or it is "-", and returns the exit code of the
+// process. Problems are written to stderr.
+func runHook(args []string, stdin io.Reader, stderr io.Writer) int {
+	fs := flag.NewFlagSet(hookCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfgPath := fs.String("config", configPath(), "path to the configuration file")
+	branch := fs.String("branch", "", "branch name to look for issue references in, defaults to the current branch")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	message, err := readHookMessage(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if err := validateHookMessage(*cfgPath, *branch, message, stderr); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// readHookMessage reads the commit message from path, or from stdin if path is empty or
+// "-", and removes the comments git adds to it.
+func readHookMessage(path string, stdin io.Reader) (string, error) {
+	var b []byte
+	var err error
+	if path == "" || path == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(path) //nolint:gosec // Why: Reading the file git passes to the hook.
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "read commit message")
+	}
+
+	return stripGitComments(string(b)), nil
+}
+
+// validateHookMessage validates message with the configuration at configPath, writing a
+// diagnosis of what is wrong with the header to stderr when it is not a conventional commit.
+func validateHookMessage(configPath, branch, message string, stderr io.Writer) error {
+	for _, prefix := range hookSkipPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return nil
+		}
+	}
+
+	cfg := &config{}
+	if b, err := os.ReadFile(configPath); err == nil { //nolint:gosec // Why: Reading the repository's own configuration.
+		if cfg, err = parseConfig(b); err != nil {
+			return errors.Wrapf(err, "parse configuration file %q", configPath)
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "read configuration file")
+	}
+
+	if cfg.NeedsChangedFiles() {
+		// Paths are separated by NUL bytes since they may contain spaces.
+		cfg.ChangedFiles = strings.FieldsFunc(gitOutput("diff", "--cached", "--name-only", "-z"), func(r rune) bool {
+			return r == 0
+		})
+	}
+
+	commit, err := cfg.Validate(message)
+	if err != nil {
+		diagnosis := explainTitleError(cfg, commitSubject(message), err)
+		for _, problem := range diagnosis.Problems {
+			fmt.Fprintf(stderr, "- %s\n", problem)
+		}
+		if diagnosis.Suggestion != "" {
+			fmt.Fprintf(stderr, "suggested header: %s\n", diagnosis.Suggestion)
+		}
+		return err
+	}
+
+	if branch == "" {
+		branch = git("symbolic-ref", "--short", "-q", "HEAD")
+	}
+	return cfg.References.Validate(commit, branch)
+}
+
+// stripGitComments removes the lines git ignores from a commit message: comments and
+// everything below the scissors line.
+func stripGitComments(message string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n") {
+		if line == gitScissors {
+			break
+		}
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// git runs git with the given arguments and returns its trimmed output, or an empty string
+// if it failed.
+func git(args ...string) string {
+	return strings.TrimSpace(gitOutput(args...))
+}
+
+// gitOutput runs git with the given arguments and returns its output as is, or an empty
+// string if it failed.
+func gitOutput(args ...string) string {
+	out, err := exec.Command("git", args...).Output() //nolint:gosec // Why: Arguments are constants from this file.
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// configPath returns the path to the configuration file, relative to the root of the
+// repository.
+func configPath() string {
+	if path := strings.TrimSpace(os.Getenv("CONFIG_PATH")); path != "" {
+		return path
+	}
+	return defaultConfigPath
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for labeling pull requests based on their
+// parsed title.
+
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/commitlint"
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// labelPullRequestEnv is the environment variable that, when set to "true", enables
+// labeling the pull request based on the type, scope and breaking marker of its title.
+const labelPullRequestEnv = "LABEL_PULL_REQUEST"
+
+// defaultBreakingLabel is the label applied to breaking changes when the configuration
+// does not specify one.
+const defaultBreakingLabel = "breaking-change"
+
+// defaultTypeLabels maps commit types to labels when the configuration does not specify
+// a mapping.
+var defaultTypeLabels = map[string]string{
+	"feat": "feature",
+	"fix":  "bugfix",
+}
+
+// labelPullRequest returns true if labeling pull requests is enabled.
+func labelPullRequest() bool {
+	return strings.TrimSpace(os.Getenv(labelPullRequestEnv)) == "true"
+}
+
+// managedLabels returns every label this action may apply according to the
+// configuration. Only these labels are ever removed from a pull request.
+func (c *labelsConfig) managedLabels() map[string]struct{} {
+	labels := map[string]struct{}{c.breakingLabel(): {}}
+	for _, label := range c.typeLabels() {
+		labels[label] = struct{}{}
+	}
+	for _, label := range c.Scopes {
+		labels[label] = struct{}{}
+	}
+	return labels
+}
+
+// desiredLabels returns the labels that should be on a pull request with the given
+// parsed title.
+func (c *labelsConfig) desiredLabels(commit *conventional.Commit) map[string]struct{} {
+	labels := make(map[string]struct{})
+	if label, ok := c.typeLabels()[commit.Type]; ok {
+		labels[label] = struct{}{}
+	}
+	for _, scope := range commitlint.SplitScopes(commit.Scope) {
+		if label, ok := c.Scopes[scope]; ok {
+			labels[label] = struct{}{}
+		}
+	}
+	if commit.Breaking {
+		labels[c.breakingLabel()] = struct{}{}
+	}
+	return labels
+}
+
+// typeLabels returns the configured type to label mapping, or defaultTypeLabels.
+func (c *labelsConfig) typeLabels() map[string]string {
+	if len(c.Types) == 0 {
+		return defaultTypeLabels
+	}
+	return c.Types
+}
+
+// breakingLabel returns the configured breaking change label, or defaultBreakingLabel.
+func (c *labelsConfig) breakingLabel() string {
+	if c.Breaking == "" {
+		return defaultBreakingLabel
+	}
+	return c.Breaking
+}
+
+// labelChanges returns the labels that need to be added to and removed from a pull request
+// that currently has the labels in current, so that it ends up with the labels desired for
+// commit. Labels that are not managed by this action are never removed.
+func labelChanges(cfg *labelsConfig, commit *conventional.Commit, current []string) (add, remove []string) {
+	desired := cfg.desiredLabels(commit)
+	managed := cfg.managedLabels()
+
+	has := make(map[string]struct{}, len(current))
+	for _, label := range current {
+		has[label] = struct{}{}
+
+		if _, isManaged := managed[label]; !isManaged {
+			continue
+		}
+		if _, isDesired := desired[label]; !isDesired {
+			remove = append(remove, label)
+		}
+	}
+
+	for label := range desired {
+		if _, ok := has[label]; !ok {
+			add = append(add, label)
+		}
+	}
+
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+// applyLabels adds the labels configured for the parsed title to the pull request and
+// removes labels that were applied for a previous title.
+func applyLabels(ctx context.Context, client *github.Client, pr *gh.PullRequest, cfg *config, commit *conventional.Commit) error {
+	org, repo := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name
+
+	current := make([]string, 0, len(pr.Labels))
+	for i := range pr.Labels {
+		current = append(current, pr.Labels[i].Name)
+	}
+
+	add, remove := labelChanges(&cfg.Labels, commit, current)
+
+	for _, label := range remove {
+		actions.Infof("removing stale label %q", label)
+		if _, err := client.Issues.RemoveLabelForIssue(ctx, org, repo, pr.Number, label); err != nil {
+			return errors.Wrapf(err, "remove label %q", label)
+		}
+	}
+
+	if len(add) > 0 {
+		actions.Infof("adding labels %q", add)
+		if _, _, err := client.Issues.AddLabelsToIssue(ctx, org, repo, pr.Number, add); err != nil {
+			return errors.Wrap(err, "add labels")
+		}
+	}
+
+	return nil
+}
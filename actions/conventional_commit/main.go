@@ -6,32 +6,17 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/getoutreach/actions/pkg/commitlint"
+	"github.com/getoutreach/actions/pkg/conventional"
 	"github.com/getoutreach/actions/pkg/gh"
 	"github.com/google/go-github/v75/github"
 	"github.com/pkg/errors"
 	actions "github.com/sethvargo/go-githubactions"
 )
 
-// allowedCommitTypes are the allowed strings in the type named capture group in the
-// reConventionalCommit regular expression.
-var allowedCommitTypes = map[string]struct{}{
-	"feat":     {},
-	"fix":      {},
-	"docs":     {},
-	"style":    {},
-	"refactor": {},
-	"perf":     {},
-	"test":     {},
-	"build":    {},
-	"ci":       {},
-	"chore":    {},
-	"revert":   {},
-}
-
 // bypassAuthorEmails are the authors that are allowed to bypass the conventional commit
 // check. This is read from the environment variable "BYPASS_AUTHOR_EMAILS", as well as
 // the defaults list below.
@@ -42,32 +27,17 @@ var bypassAuthorEmails = map[string]struct{}{
 	"49699333+dependabot[bot]@users.noreply.github.com": {},
 }
 
-// Variable block for regular expression parsing.
-var (
-	// reConventionalCommit is a regular expression that matches a valid conventional
-	// commit title.
-	//
-	// For examples, see https://regex101.com/r/gkNDNK/1
-	reConventionalCommit = regexp.MustCompile(`^(?P<type>\w+)(?P<scope>\([-\w\/]+\))?(?P<breaking>!)?:\s(?P<message>.*?)$`)
-
-	// reConventionalCommitType stores the index of the type named capture group for
-	// reConventionalCommit.
-	reConventionalCommitType = reConventionalCommit.SubexpIndex("type")
-
-	// reConventionalCommitScope stores the index of the scope named capture group for
-	// reConventionalCommit.
-	reConventionalCommitScope = reConventionalCommit.SubexpIndex("scope")
-
-	// reConventionalCommitBreaking stores the index of the breaking named capture
-	// group for reConventionalCommit.
-	reConventionalCommitBreaking = reConventionalCommit.SubexpIndex("breaking")
-
-	// reConventionalCommitMessage stores the index of the message named capture
-	// group for reConventionalCommit.
-	reConventionalCommitMessage = reConventionalCommit.SubexpIndex("message")
-)
+// validateCommitsEnv is the environment variable that, when set to "true", enables
+// validating the subject of every commit on the pull request in addition to the pull
+// request title. This is useful for repositories that rebase-merge, where every commit
+// ends up on the default branch.
+const validateCommitsEnv = "VALIDATE_COMMITS"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == hookCommand {
+		os.Exit(runHook(os.Args[2:], os.Stdin, os.Stderr))
+	}
+
 	exitCode := 1
 	defer func() {
 		os.Exit(exitCode)
@@ -98,6 +68,12 @@ func main() {
 // allowBypass checks if the commit author is allowed to bypass the conventional commit
 // check.
 func allowBypass(commit *github.RepositoryCommit) bool {
+	return commitBypassReason(commit) != ""
+}
+
+// commitBypassReason returns why the commit is allowed to bypass the conventional commit
+// check, or an empty string if it is not allowed to.
+func commitBypassReason(commit *github.RepositoryCommit) string {
 	// Read emails from BYPASS_AUTHOR_EMAILS env var in bypassAuthorEmails.
 	//
 	// Note: This parsing is here to make it easy to unit test.
@@ -108,83 +84,370 @@ func allowBypass(commit *github.RepositoryCommit) bool {
 	}
 
 	// check if the commit is by an author that is allowed to bypass the check.
+	var reason string
 	authorEmail := commit.GetCommit().GetAuthor().GetEmail()
+	authorLogin := commit.GetAuthor().GetLogin()
 	if _, ok := bypassAuthorEmails[authorEmail]; ok {
-		actions.Infof("author %q is allowed to bypass conventional commit check", authorEmail)
+		reason = fmt.Sprintf("commit author email %q is in the bypass list", authorEmail)
+	} else if _, ok := bypassLogins()[authorLogin]; ok && authorLogin != "" {
+		reason = fmt.Sprintf("commit author %q is in %s", authorLogin, bypassLoginsEnv)
+	}
 
-		// to ensure that someone doesn't try to bypass this check by spoofing the email
-		// address, we check that the commit has a valid GPG signature (according to Github).
-		if !commit.GetCommit().GetVerification().GetVerified() {
-			actions.Errorf("commit %q is not signed, not bypassing check", commit.GetSHA())
-		} else {
-			// the commit is signed and from an approved email, so we can bypass the check.
-			return true
-		}
+	if reason == "" {
+		// always default to not allowing bypass.
+		return ""
 	}
 
-	// always default to not allowing bypass.
-	return false
-}
+	actions.Infof("commit %q is allowed to bypass conventional commit check: %s", commit.GetSHA(), reason)
 
-// validateCommitMessage checks if the commit message meets conventional commit requirement or not.
-func validateCommitMessage(commitMessage string) error {
-	matches := reConventionalCommit.FindStringSubmatch(commitMessage)
-	if matches == nil {
-		return errors.New("pr title does not match conventional commit syntax")
+	// to ensure that someone doesn't try to bypass this check by spoofing the email
+	// address, we check that the commit has a valid GPG signature (according to Github).
+	if !commit.GetCommit().GetVerification().GetVerified() {
+		actions.Errorf("commit %q is not signed, not bypassing check", commit.GetSHA())
+		return ""
 	}
 
-	cType := matches[reConventionalCommitType]
-	scope := matches[reConventionalCommitScope]
-	breaking := matches[reConventionalCommitBreaking]
-	message := matches[reConventionalCommitMessage]
+	// the commit is signed and from an approved author, so we can bypass the check.
+	return reason
+}
 
-	if _, exists := allowedCommitTypes[cType]; !exists {
-		return fmt.Errorf("commit type %q is not in the list of allowed commit types", cType)
+// validateCommitMessage parses the commit message and checks if it meets conventional commit
+// requirement or not, taking into account the repository's configuration. See
+// commitlint.Config.Validate for what is checked.
+func validateCommitMessage(cfg *config, commitMessage string) (*conventional.Commit, error) {
+	commit, err := cfg.Validate(commitMessage)
+	if err != nil {
+		return nil, err
 	}
 
-	actions.Infof("successfully parsed conventional commit:\ntype: [%s]\nscope: [%s]\nbreaking: [%t]\nmessage: [%s]",
-		cType, strings.TrimSuffix(strings.TrimPrefix(scope, "("), ")"), breaking == "!", message)
+	actions.Infof("successfully parsed conventional commit:\ntype: [%s]\nscope: [%s]\nbreaking: [%t]\nmessage: [%s]\nfooters: [%d]",
+		commit.Type, commit.Scope, commit.Breaking, commit.Description, len(commit.Footers))
 
-	return nil
+	return commit, nil
 }
 
 // RunAction is where the actual implementation of the GitHub action goes and is called
 // by func main.
 func RunAction(ctx context.Context, client *github.Client, actionCtx *actions.GitHubContext) error {
-	if actionCtx.EventName != "pull_request" {
-		return errors.New("conventional_commit running on non \"pull_request\" event")
+	if baseTag := releaseBaseTag(); baseTag != "" {
+		return runRelease(ctx, client, actionCtx, baseTag)
+	}
+
+	switch en := actionCtx.EventName; en {
+	case "pull_request", "pull_request_target":
+		return runOnPullRequestEvent(ctx, client, actionCtx)
+	case "merge_group":
+		return runOnMergeGroup(ctx, client, actionCtx)
+	default:
+		return fmt.Errorf("conventional_commit running on unsupported event %q", en)
 	}
+}
 
+// runOnPullRequestEvent validates the pull request of a pull_request or
+// pull_request_target event, unless it was edited in a way that can't change the result.
+func runOnPullRequestEvent(ctx context.Context, client *github.Client, actionCtx *actions.GitHubContext) error {
 	pr, err := gh.ParsePullRequestPayload(actionCtx.Event)
 	if err != nil {
 		return errors.Wrap(err, "parse event payload")
 	}
 
+	event, err := gh.ParsePullRequestEventPayload(actionCtx.Event)
+	if err != nil {
+		return errors.Wrap(err, "parse event payload")
+	}
+
+	if reason := skipCheckReason(ctx, client, event, pr); reason != "" {
+		actions.Infof("skipping conventional commit check: %s", reason)
+		return nil
+	}
+
+	policy, err := draftPolicy()
+	if err != nil {
+		return err
+	}
+
+	policy = pullRequestPolicy(pr, event, policy)
+	if policy == draftPolicySkip {
+		actions.Infof("skipping conventional commit check: pull request #%d is a draft", pr.Number)
+		return nil
+	}
+
+	return runOnPullRequest(ctx, client, newTeamClient(ctx, client), pr, previousTitle(event), policy == draftPolicyWarn)
+}
+
+// runOnPullRequest validates a single pull request and reports the result on it through
+// comments, check runs, labels and outputs, depending on what is enabled. previousTitle is
+// the title before it was edited, if it was, and is used to report when a title was fixed.
+// When warnOnly is true failures are reported as warnings instead of failing the check,
+// which is how draft pull requests are checked with the draftPolicyWarn policy. teamClient
+// is used to read team memberships, see newTeamClient.
+func runOnPullRequest(ctx context.Context, client, teamClient *github.Client, pr *gh.PullRequest, previousTitle string, warnOnly bool) error { //nolint:lll // Why: Function signature.
+	org, repo := pr.Base.Repo.Owner.Login, pr.Base.Repo.Name
+
+	rep, cfg, err := checkPullRequest(ctx, client, teamClient, pr)
+	if err != nil {
+		if name := checkRunName(); name != "" {
+			if err := publishErrorCheckRun(ctx, client, org, repo, pr.Head.SHA, name, err); err != nil {
+				actions.Warningf("unable to publish check run: %v", err)
+			}
+		}
+		return err
+	}
+	rep.WarnOnly = warnOnly
+
+	// The check run is published before anything else so that it exists even for pull
+	// requests that bypassed the check, in case it is a required check.
+	if name := checkRunName(); name != "" {
+		if err := publishCheckRun(ctx, client, org, repo, pr.Head.SHA, name, rep); err != nil {
+			actions.Warningf("unable to publish check run: %v", err)
+		}
+	}
+
+	if rep.Bypass != "" {
+		actions.Noticef("pull request #%d bypassed the conventional commit check: %s", pr.Number, rep.Bypass)
+		gh.AddStepSummary(rep.markdownSummary())
+		return nil
+	}
+
+	if gh.CommentOnFailure() {
+		updateFailureComment(ctx, client, pr, cfg, rep.TitleErr)
+	}
+
+	if rep.bypassedCommits() > 0 {
+		gh.AddStepSummary(rep.markdownSummary())
+	}
+
+	if err := rep.err(); err != nil {
+		if rep.WarnOnly {
+			actions.Warningf("draft pull request #%d will fail the conventional commit check once it is ready for review:\n%v",
+				pr.Number, err)
+			return nil
+		}
+		return err
+	}
+
+	if previousTitle != "" {
+		if _, err := cfg.Validate(previousTitle + "\n\n" + pr.Body); err != nil {
+			actions.Noticef("pull request title fixed, it was %q", previousTitle)
+			gh.AddStepSummary(titleFixedSummary(previousTitle, pr.Title, err))
+		}
+	}
+
+	if labelPullRequest() {
+		if err := applyLabels(ctx, client, pr, cfg, rep.Commit); err != nil {
+			actions.Warningf("unable to label pull request: %v", err)
+		}
+	}
+
+	if err := setJSONOutput("commit", rep.Commit); err != nil {
+		return err
+	}
+
+	gh.AddStepSummary(changelogPreview(pr, rep))
+	setCommitOutputs(rep)
+	if rep.Commits == nil {
+		return nil
+	}
+	return setJSONOutput("commits", rep.parsedCommits())
+}
+
+// checkPullRequest validates the title, and optionally the commits, of a single pull request
+// without reporting the result anywhere. If the pull request is allowed to bypass the check
+// the returned report only has Bypass set, and the returned config is nil. The returned
+// error is only set when the pull request could not be checked, validation failures are
+// recorded in the report. teamClient is used to read team memberships, see newTeamClient.
+func checkPullRequest(ctx context.Context, client, teamClient *github.Client, pr *gh.PullRequest) (*report, *config, error) {
 	actions.Infof("PR title (sans quotes): %q", pr.Title)
 	actions.Infof("number of commits: %d", pr.Commits)
 
+	rep := &report{Number: pr.Number, Title: pr.Title}
+
+	bypass, err := pullRequestBypassReason(ctx, client, teamClient, pr)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "check bypass rules")
+	}
+	if bypass != "" {
+		rep.Bypass = bypass
+		return rep, nil, nil
+	}
+
 	if pr.Commits == 1 {
 		// The title of the first commit and the PR title need to match in this case.
 		commit, _, err := client.Repositories.GetCommit(ctx, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Head.SHA, &github.ListOptions{})
 		if err != nil {
-			return errors.Wrap(err, "get first commit details from github api")
+			return nil, nil, errors.Wrap(err, "get first commit details from github api")
 		}
 
 		// check if the commit author is allowed to bypass the conventional commit check.
-		if allowBypass(commit) {
-			return nil
+		if bypass := commitBypassReason(commit); bypass != "" {
+			rep.Bypass = bypass
+			return rep, nil, nil
 		}
 
-		message := commit.GetCommit().GetMessage()
-		parsedMessage := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
-		commitTitle := parsedMessage[0]
+		commitTitle := commitSubject(commit.GetCommit().GetMessage())
 
 		actions.Infof("parsed title of first commit (sans quotes): %q", commitTitle)
 
 		if strings.TrimSpace(commitTitle) != strings.TrimSpace(pr.Title) {
-			return errors.New("since branch has 1 commit, PR title and commit title must match and both be in conventional commit format")
+			rep.SingleCommitErr = errors.New(
+				"since branch has 1 commit, PR title and commit title must match and both be in conventional commit format")
 		}
 	}
 
-	return validateCommitMessage(pr.Title)
+	cfg, err := loadConfig(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Base.Ref)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "load configuration")
+	}
+
+	if cfg.NeedsChangedFiles() {
+		cfg.ChangedFiles, err = gh.ListAllPullRequestFiles(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "list pull request files")
+		}
+	}
+
+	rep.Commit, rep.TitleErr = validatePullRequestTitle(ctx, client, cfg, pr)
+
+	if validateSquashMessageEnabled() {
+		rep.SquashMessage = squashMessage(pr)
+		rep.SquashErr = validateSquashMessage(cfg, rep.SquashMessage)
+	}
+
+	if err := checkPullRequestCommits(ctx, client, cfg, pr, rep); err != nil {
+		return nil, nil, err
+	}
+
+	return rep, cfg, nil
+}
+
+// checkPullRequestCommits validates the commits on the pull request, if commit validation
+// or the autosquash check is enabled, and adds the results to rep.
+func checkPullRequestCommits(ctx context.Context, client *github.Client, cfg *config, pr *gh.PullRequest, rep *report) error {
+	validate := strings.TrimSpace(os.Getenv(validateCommitsEnv)) == "true"
+	autosquash := rejectAutosquashCommits()
+	if !validate && !autosquash {
+		return nil
+	}
+
+	commits, err := gh.ListAllPullRequestCommits(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number)
+	if err != nil {
+		return errors.Wrap(err, "list pull request commits")
+	}
+
+	if validate {
+		if cfg.NeedsChangedFiles() {
+			if err := getCommitFiles(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, commits); err != nil {
+				return err
+			}
+		}
+		rep.Commits, rep.CommitsErr = validateCommits(cfg, commits)
+	}
+
+	if autosquash {
+		wip, err := wipPattern()
+		if err != nil {
+			return err
+		}
+		rep.AutosquashErr = validateAutosquashCommits(commits, wip, pr.Base.Ref)
+	}
+	return nil
+}
+
+// errInvalidTitleSyntax is returned by validatePullRequestTitle in place of
+// commitlint.ErrInvalidSyntax, which is about commit messages in general.
+var errInvalidTitleSyntax = errors.New("pr title does not match conventional commit syntax")
+
+// validatePullRequestTitle validates the title and description of the pull request as a
+// commit message, along with the rules that only apply to pull requests: issue references
+// and revert targets.
+func validatePullRequestTitle(ctx context.Context, client *github.Client, cfg *config, pr *gh.PullRequest) (*conventional.Commit, error) {
+	// The PR body is parsed along with the title so that footers like BREAKING CHANGE
+	// that are written in the description are taken into account.
+	commit, err := validateCommitMessage(cfg, pr.Title+"\n\n"+pr.Body)
+	if errors.Is(err, commitlint.ErrInvalidSyntax) {
+		return nil, errInvalidTitleSyntax
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.References.Validate(commit, pr.Head.Ref); err != nil {
+		return commit, err
+	}
+
+	if err := validateRevert(ctx, client, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, commit); err != nil {
+		return commit, err
+	}
+	return commit, nil
+}
+
+// commitSubject returns the first line of a commit message.
+func commitSubject(message string) string {
+	return strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")[0]
+}
+
+// getCommitFiles sets the files changed by each of the commits, which the API does not
+// include when listing the commits of a pull request.
+func getCommitFiles(ctx context.Context, client *github.Client, org, repo string, commits []*github.RepositoryCommit) error {
+	for _, commit := range commits {
+		full, _, err := client.Repositories.GetCommit(ctx, org, repo, commit.GetSHA(), &github.ListOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "get files changed by commit %s", commit.GetSHA())
+		}
+		commit.Files = full.Files
+	}
+	return nil
+}
+
+// commitConfig returns the configuration to validate a single commit with. When scopes are
+// restricted to paths, the changed files are the ones changed by the commit rather than by
+// the whole pull request, so that a commit is not rejected for areas that only other
+// commits changed.
+func commitConfig(cfg *config, commit *github.RepositoryCommit) *config {
+	if !cfg.NeedsChangedFiles() {
+		return cfg
+	}
+
+	commitCfg := *cfg
+	commitCfg.ChangedFiles = make([]string, 0, len(commit.Files))
+	for _, file := range commit.Files {
+		commitCfg.ChangedFiles = append(commitCfg.ChangedFiles, file.GetFilename())
+	}
+	return &commitCfg
+}
+
+// validateCommits runs validateCommitMessage on the message of every commit passed to it,
+// skipping commits that are allowed to bypass the check. Rather than stopping at the first
+// invalid commit, every commit is validated and every offending commit is reported in the
+// returned error. Scopes restricted to paths are checked against the files each commit
+// changed, see commitConfig.
+func validateCommits(cfg *config, commits []*github.RepositoryCommit) ([]commitResult, error) {
+	var failures []string
+	results := make([]commitResult, 0, len(commits))
+	for _, commit := range commits {
+		message := commit.GetCommit().GetMessage()
+		result := commitResult{
+			SHA:     commit.GetSHA(),
+			Subject: commitSubject(message),
+		}
+
+		if result.Bypass = commitBypassReason(commit); result.Bypass != "" {
+			results = append(results, result)
+			continue
+		}
+
+		result.Commit, result.Err = validateCommitMessage(commitConfig(cfg, commit), message)
+		if result.Err != nil {
+			failures = append(failures, fmt.Sprintf("- %s %q: %v", result.SHA, result.Subject, result.Err))
+		}
+		results = append(results, result)
+	}
+
+	if len(failures) > 0 {
+		return results, fmt.Errorf("%d of %d commits are not in conventional commit format:\n%s",
+			len(failures), len(commits), strings.Join(failures, "\n"))
+	}
+
+	actions.Infof("all %d commits are in conventional commit format", len(commits))
+	return results, nil
 }
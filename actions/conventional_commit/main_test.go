@@ -3,13 +3,61 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/getoutreach/actions/pkg/commitlint"
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v75/github"
 	"gotest.tools/v3/assert"
 )
 
+// newTestClient returns a GitHub client that sends every request to handler instead of the
+// GitHub API.
+func newTestClient(t *testing.T, handler http.Handler) *github.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	baseURL, err := url.Parse(server.URL + "/")
+	assert.NilError(t, err)
+
+	client := github.NewClient(nil)
+	client.BaseURL = baseURL
+	return client
+}
+
+// newCommit returns a commit of the getoutreach/pencil repository with the given SHA and
+// message that changes files, as returned by the GitHub API.
+func newCommit(sha, message string, files ...string) *github.RepositoryCommit {
+	commit := &github.RepositoryCommit{
+		SHA:     github.Ptr(sha),
+		HTMLURL: github.Ptr("https://github.com/getoutreach/pencil/commit/" + sha),
+		Commit:  &github.Commit{Message: github.Ptr(message)},
+	}
+	for _, file := range files {
+		commit.Files = append(commit.Files, &github.CommitFile{Filename: github.Ptr(file)})
+	}
+	return commit
+}
+
+// newPR returns a pull request with the given title and body.
+func newPR(title, body string) *gh.PullRequest {
+	return &gh.PullRequest{Title: title, Body: body, Number: 1230}
+}
+
 func Test_allowBypass(t *testing.T) {
 	type args struct {
 		commit *github.RepositoryCommit
@@ -17,6 +65,7 @@ func Test_allowBypass(t *testing.T) {
 	tests := []struct {
 		name                  string
 		bypassAuthorEmailsEnv string
+		bypassLoginsEnv       string
 		args                  args
 		want                  bool
 	}{
@@ -86,6 +135,46 @@ func Test_allowBypass(t *testing.T) {
 			bypassAuthorEmailsEnv: "jaredallard@users.noreply.github.com",
 			want:                  true,
 		},
+		{
+			name: "should read bypass logins from env",
+			args: args{
+				commit: &github.RepositoryCommit{
+					Author: &github.User{
+						Login: github.Ptr("renovate[bot]"),
+					},
+					Commit: &github.Commit{
+						Author: &github.CommitAuthor{
+							Email: github.Ptr("29139614+renovate[bot]@users.noreply.github.com"),
+						},
+						Verification: &github.SignatureVerification{
+							Verified: github.Ptr(true),
+						},
+					},
+				},
+			},
+			bypassLoginsEnv: "dependabot[bot] renovate[bot]",
+			want:            true,
+		},
+		{
+			name: "should not allow bypass for login in env with unverified commit",
+			args: args{
+				commit: &github.RepositoryCommit{
+					Author: &github.User{
+						Login: github.Ptr("renovate[bot]"),
+					},
+					Commit: &github.Commit{
+						Author: &github.CommitAuthor{
+							Email: github.Ptr("29139614+renovate[bot]@users.noreply.github.com"),
+						},
+						Verification: &github.SignatureVerification{
+							Verified: github.Ptr(false),
+						},
+					},
+				},
+			},
+			bypassLoginsEnv: "renovate[bot]",
+			want:            false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -93,6 +182,7 @@ func Test_allowBypass(t *testing.T) {
 				fmt.Printf("BYPASS_AUTHOR_EMAILS=%q\n", tt.bypassAuthorEmailsEnv)
 				t.Setenv("BYPASS_AUTHOR_EMAILS", tt.bypassAuthorEmailsEnv)
 			}
+			t.Setenv("BYPASS_LOGINS", tt.bypassLoginsEnv)
 
 			if got := allowBypass(tt.args.commit); got != tt.want {
 				t.Errorf("allowBypass() = %v, want %v", got, tt.want)
@@ -103,6 +193,7 @@ func Test_allowBypass(t *testing.T) {
 
 func Test_validateCommitMessage(t *testing.T) {
 	type args struct {
+		cfg           *config
 		commitMessage string
 	}
 	tests := []struct {
@@ -124,12 +215,19 @@ func Test_validateCommitMessage(t *testing.T) {
 			},
 			errMsg: "",
 		},
+		{
+			name: "github revert",
+			args: args{
+				commitMessage: "Revert \"feat(pencil): add 'graphiteWidth' option\"\n\nReverts getoutreach/pencil#12",
+			},
+			errMsg: "",
+		},
 		{
 			name: "feat without space",
 			args: args{
 				commitMessage: "feat(pencil):add 'graphiteWidth' option",
 			},
-			errMsg: "pr title does not match conventional commit syntax",
+			errMsg: "commit message does not match conventional commit syntax",
 		},
 		{
 			name: "invalid type",
@@ -138,10 +236,355 @@ func Test_validateCommitMessage(t *testing.T) {
 			},
 			errMsg: "commit type \"invalid\" is not in the list of allowed commit types",
 		},
+		{
+			name: "type added by config",
+			args: args{
+				cfg:           &config{Config: commitlint.Config{Types: commitlint.TypesConfig{Add: []string{"deps"}}}},
+				commitMessage: "deps: bump graphite to v2",
+			},
+			errMsg: "",
+		},
+		{
+			name: "type removed by config",
+			args: args{
+				cfg:           &config{Config: commitlint.Config{Types: commitlint.TypesConfig{Remove: []string{"style"}}}},
+				commitMessage: "style: reformat pencil",
+			},
+			errMsg: "commit type \"style\" is not in the list of allowed commit types",
+		},
+		{
+			name: "missing required scope",
+			args: args{
+				cfg:           &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Required: true}}},
+				commitMessage: "fix: stop graphite breaking",
+			},
+			errMsg: "commit scope is required",
+		},
+		{
+			name: "scope not in allow-list",
+			args: args{
+				cfg: &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Allowed: []commitlint.ScopeConfig{
+					{Name: "pencil"},
+					{Name: "eraser"},
+				}}}},
+				commitMessage: "fix(pen): stop ink leaking",
+			},
+			errMsg: "commit scope \"pen\" is not in the list of allowed scopes (eraser, pencil)",
+		},
+		{
+			name: "scope matches changed path",
+			args: args{
+				cfg: &config{Config: commitlint.Config{
+					Scopes: commitlint.ScopesConfig{Allowed: []commitlint.ScopeConfig{
+						{Name: "pencil", Paths: []string{"pencil/**"}},
+					}},
+					ChangedFiles: []string{"pencil/graphite.go"},
+				}},
+				commitMessage: "fix(pencil): stop graphite breaking",
+			},
+			errMsg: "",
+		},
+		{
+			name: "scope does not match changed path",
+			args: args{
+				cfg: &config{Config: commitlint.Config{
+					Scopes: commitlint.ScopesConfig{Allowed: []commitlint.ScopeConfig{
+						{Name: "pencil", Paths: []string{"pencil/**"}},
+					}},
+					ChangedFiles: []string{"eraser/rubber.go"},
+				}},
+				commitMessage: "fix(pencil): stop graphite breaking",
+			},
+			errMsg: "commit scope \"pencil\" is only allowed for changes to pencil/**",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.args.cfg
+			if cfg == nil {
+				cfg = &config{}
+			}
+
+			_, err := validateCommitMessage(cfg, tt.args.commitMessage)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func Test_validateCommitMessage_parser(t *testing.T) {
+	gitmoji := &config{Config: commitlint.Config{Gitmoji: true}}
+	dots := &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Pattern: `[-\w./]+`}}}
+	unicode := &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Pattern: `[-\p{L}\p{N}_/]+`}}}
+
+	tests := []struct {
+		name          string
+		cfg           *config
+		commitMessage string
+		want          *conventional.Commit
+		errMsg        string
+	}{
+		{
+			name:          "emoji in description without gitmoji",
+			cfg:           &config{},
+			commitMessage: "feat(ui): ✨ add picker",
+			want:          &conventional.Commit{Type: "feat", Scope: "ui", Description: "✨ add picker"},
+		},
+		{
+			name:          "emoji before type without gitmoji",
+			cfg:           &config{},
+			commitMessage: "✨ feat(ui): add picker",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+		{
+			name:          "emoji before type",
+			cfg:           gitmoji,
+			commitMessage: "✨ feat(ui): add picker",
+			want:          &conventional.Commit{Type: "feat", Scope: "ui", Description: "add picker", Gitmoji: "✨"},
+		},
+		{
+			name:          "emoji in description",
+			cfg:           gitmoji,
+			commitMessage: "feat(ui): ✨ add picker",
+			want:          &conventional.Commit{Type: "feat", Scope: "ui", Description: "add picker", Gitmoji: "✨"},
+		},
+		{
+			name:          "emoji with variation selector",
+			cfg:           gitmoji,
+			commitMessage: "⚡️ perf: cache pickers",
+			want:          &conventional.Commit{Type: "perf", Description: "cache pickers", Gitmoji: "⚡️"},
+		},
+		{
+			name:          "shortcode",
+			cfg:           gitmoji,
+			commitMessage: ":bug: fix(api)!: stop dropping requests",
+			want:          &conventional.Commit{Type: "fix", Scope: "api", Breaking: true, Description: "stop dropping requests", Gitmoji: ":bug:"},
+		},
+		{
+			name:          "emoji without space",
+			cfg:           gitmoji,
+			commitMessage: "✨feat(ui): add picker",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+		{
+			name:          "dot in scope by default",
+			cfg:           &config{},
+			commitMessage: "fix(api.v2): stop dropping requests",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+		{
+			name:          "dot in scope",
+			cfg:           dots,
+			commitMessage: "fix(api.v2): stop dropping requests",
+			want:          &conventional.Commit{Type: "fix", Scope: "api.v2", Description: "stop dropping requests"},
+		},
+		{
+			name:          "unicode scope by default",
+			cfg:           &config{},
+			commitMessage: "fix(über): stop dropping requests",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+		{
+			name:          "unicode scope",
+			cfg:           unicode,
+			commitMessage: "fix(über): stop dropping requests",
+			want:          &conventional.Commit{Type: "fix", Scope: "über", Description: "stop dropping requests"},
+		},
+		{
+			name:          "empty scope",
+			cfg:           unicode,
+			commitMessage: "fix(): stop dropping requests",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateCommitMessage(tt.cfg, tt.commitMessage)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NilError(t, err)
+			tt.want.Header = tt.commitMessage
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func Test_validatePullRequestTitle(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		errMsg string
+	}{
+		{
+			name:  "valid",
+			title: "fix(pencil): stop graphite breaking",
+		},
+		{
+			name:   "invalid syntax",
+			title:  "feat(pencil):add graphite width",
+			errMsg: "pr title does not match conventional commit syntax",
+		},
+		{
+			name:   "type not allowed",
+			title:  "invalid: add graphite width",
+			errMsg: "commit type \"invalid\" is not in the list of allowed commit types",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validatePullRequestTitle(context.Background(), nil, &config{}, newPR(tt.title, ""))
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func Test_validateCommits(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []*github.RepositoryCommit
+		errMsg  string
+	}{
+		{
+			name: "all valid",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(pencil): add 'graphiteWidth' option"),
+				newCommit("bbb", "fix: stop graphite breaking\r\n\r\nSome body text."),
+			},
+		},
+		{
+			name: "reports every invalid commit",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(pencil): add 'graphiteWidth' option"),
+				newCommit("bbb", "oops"),
+				newCommit("ccc", "invalid: add eraser"),
+			},
+			errMsg: "2 of 3 commits are not in conventional commit format:\n" +
+				"- bbb \"oops\": commit message does not match conventional commit syntax\n" +
+				"- ccc \"invalid: add eraser\": commit type \"invalid\" is not in the list of allowed commit types",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateCommits(&config{}, tt.commits)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func Test_validateCommits_enforcePaths(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+scopes:
+  enforce_paths: true
+  allowed:
+    - name: api
+      paths: ["api/**"]
+    - name: web
+      paths: ["web/**"]
+`))
+	assert.NilError(t, err)
+	// The pull request as a whole changes both areas.
+	cfg.ChangedFiles = []string{"api/server.go", "web/index.ts"}
+
+	tests := []struct {
+		name    string
+		commits []*github.RepositoryCommit
+		errMsg  string
+	}{
+		{
+			name: "each commit scoped to its own area",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(api): add pencil endpoint", "api/server.go"),
+				newCommit("bbb", "feat(web): add pencil page", "web/index.ts"),
+			},
+		},
+		{
+			name: "commit scoped to another area",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(api): add pencil endpoint", "api/server.go"),
+				newCommit("bbb", "feat(api): add pencil page", "web/index.ts"),
+			},
+			errMsg: "1 of 2 commits are not in conventional commit format:\n" +
+				"- bbb \"feat(api): add pencil page\": the changes are in the \"web\" area, use it as the commit scope: (web)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateCommits(cfg, tt.commits)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func Test_validateAutosquashCommits(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		commits []*github.RepositoryCommit
+		errMsg  string
+	}{
+		{
+			name: "no commits to squash",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(pencil): add 'graphiteWidth' option"),
+				newCommit("bbb", "fix: wipe graphite dust\n\nfixup! is mentioned in the body."),
+			},
+		},
+		{
+			name: "reports every commit to squash",
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "feat(pencil): add 'graphiteWidth' option"),
+				newCommit("bbb", "fixup! feat(pencil): add 'graphiteWidth' option"),
+				newCommit("ccc", "squash! feat(pencil): add 'graphiteWidth' option\n\nMore details."),
+				newCommit("ddd", "amend! feat(pencil): add 'graphiteWidth' option"),
+				newCommit("eee", "WIP"),
+				newCommit("fff", "[wip] eraser"),
+			},
+			errMsg: "5 commit(s) must be squashed or reworded before merging, " +
+				"run `git rebase --interactive --autosquash origin/main` and force push:\n" +
+				"- bbb \"fixup! feat(pencil): add 'graphiteWidth' option\"\n" +
+				"- ccc \"squash! feat(pencil): add 'graphiteWidth' option\"\n" +
+				"- ddd \"amend! feat(pencil): add 'graphiteWidth' option\"\n" +
+				"- eee \"WIP\"\n" +
+				"- fff \"[wip] eraser\"",
+		},
+		{
+			name:    "custom wip pattern",
+			pattern: `^(?:tmp|DO NOT MERGE)\b`,
+			commits: []*github.RepositoryCommit{
+				newCommit("aaa", "wipe: not a work in progress commit"),
+				newCommit("bbb", "DO NOT MERGE debugging"),
+			},
+			errMsg: "1 commit(s) must be squashed or reworded before merging, " +
+				"run `git rebase --interactive --autosquash origin/main` and force push:\n" +
+				"- bbb \"DO NOT MERGE debugging\"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateCommitMessage(tt.args.commitMessage)
+			t.Setenv(wipPatternEnv, tt.pattern)
+
+			wip, err := wipPattern()
+			assert.NilError(t, err)
+
+			err = validateAutosquashCommits(tt.commits, wip, "main")
 			if tt.errMsg != "" {
 				assert.Error(t, err, tt.errMsg)
 			} else {
@@ -150,3 +593,1089 @@ func Test_validateCommitMessage(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   *config
+		errMsg string
+	}{
+		{
+			name:   "empty",
+			config: "",
+			want:   &config{},
+		},
+		{
+			name: "types and scopes",
+			config: `types:
+  add: [deps]
+  remove: [style]
+scopes:
+  required: true
+  allowed:
+    - name: api
+      paths: ["services/api/**"]
+    - name: deps
+`,
+			want: &config{Config: commitlint.Config{
+				Types: commitlint.TypesConfig{Add: []string{"deps"}, Remove: []string{"style"}},
+				Scopes: commitlint.ScopesConfig{
+					Required: true,
+					Allowed: []commitlint.ScopeConfig{
+						{Name: "api", Paths: []string{"services/api/**"}},
+						{Name: "deps"},
+					},
+				},
+			}},
+		},
+		{
+			name: "scope without name",
+			config: `scopes:
+  allowed:
+    - paths: ["services/api/**"]
+`,
+			errMsg: "scopes in the allow-list must have a name",
+		},
+		{
+			name: "invalid path pattern",
+			config: `scopes:
+  allowed:
+    - name: api
+      paths: ["services/[api"]
+`,
+			errMsg: "scope \"api\" has invalid path pattern \"services/[api\"",
+		},
+		{
+			name:   "invalid scope pattern",
+			config: "scopes:\n  pattern: '[a-z]+)|(.*'\n",
+			errMsg: "invalid scope pattern \"[a-z]+)|(.*\": error parsing regexp: unexpected ): `[a-z]+)|(.*`",
+		},
+		{
+			name: "invalid reference pattern",
+			config: `references:
+  types: [feat]
+  patterns:
+    - name: Jira
+      pattern: "[A-Z"
+`,
+			errMsg: "invalid reference pattern \"[A-Z\": error parsing regexp: missing closing ]: `[A-Z`",
+		},
+		{
+			name:   "invalid Jira project key",
+			config: "references:\n  types: [feat]\n  jira_projects: [dt-]\n",
+			errMsg: "invalid Jira project key \"dt-\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConfig([]byte(tt.config))
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want, cmp.AllowUnexported(config{}))
+		})
+	}
+}
+
+func Test_diagnoseTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *config
+		title string
+		want  *titleDiagnosis
+	}{
+		{
+			name:  "missing space after colon",
+			title: "feat(pencil):add 'graphiteWidth' option",
+			want: &titleDiagnosis{
+				Problems:   []string{"the colon after the type must be followed by exactly one space"},
+				Suggestion: "feat(pencil): add 'graphiteWidth' option",
+			},
+		},
+		{
+			name:  "missing colon",
+			title: "fix stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"the type and description must be separated by a colon followed by a space (`: `)"},
+				Suggestion: "fix: stop graphite breaking",
+			},
+		},
+		{
+			name:  "missing type",
+			title: "Add eraser to pencil",
+			want: &titleDiagnosis{
+				Problems: []string{
+					"the type and description must be separated by a colon followed by a space (`: `)",
+					"the title must start with a type, e.g. `feat: `",
+				},
+				Suggestion: "feat: Add eraser to pencil",
+			},
+		},
+		{
+			name:  "unknown type without guess",
+			title: "Pencil improvements",
+			want: &titleDiagnosis{
+				Problems: []string{
+					"the type and description must be separated by a colon followed by a space (`: `)",
+					"the title must start with a type, e.g. `feat: `",
+				},
+				Suggestion: "<type>: Pencil improvements",
+			},
+		},
+		{
+			name:  "uppercase type",
+			title: "Fix: stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"the type `Fix` must be lowercase"},
+				Suggestion: "fix: stop graphite breaking",
+			},
+		},
+		{
+			name:  "common type mistake",
+			title: "feature(pencil)!: drop ballpoint support",
+			want: &titleDiagnosis{
+				Problems:   []string{"the type `feature` is not allowed, did you mean `feat`?"},
+				Suggestion: "feat(pencil)!: drop ballpoint support",
+			},
+		},
+		{
+			name:  "unknown type",
+			title: "invalid(pencil): add 'graphiteWidth' option",
+			want: &titleDiagnosis{
+				Problems:   []string{"the type `invalid` is not in the list of allowed types"},
+				Suggestion: "<type>(pencil): add 'graphiteWidth' option",
+			},
+		},
+		{
+			name:  "invalid scope characters",
+			title: "fix(pencil lead): stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"the scope `pencil lead` may only contain letters, numbers, `_`, `-` and `/`"},
+				Suggestion: "fix(pencil-lead): stop graphite breaking",
+			},
+		},
+		{
+			name:  "scope allowed by the configured pattern",
+			cfg:   &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Pattern: `[-\w./]+`}}},
+			title: "fix(pencil.lead):stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"the colon after the type must be followed by exactly one space"},
+				Suggestion: "fix(pencil.lead): stop graphite breaking",
+			},
+		},
+		{
+			name:  "scope not allowed by the configured pattern",
+			cfg:   &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Pattern: `[a-z]+`}}},
+			title: "fix(Pencil_Lead): stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"the scope `Pencil_Lead` must match the pattern `[a-z]+`"},
+				Suggestion: "fix(Pencil_Lead): stop graphite breaking",
+			},
+		},
+		{
+			name:  "gitmoji in front of the type",
+			cfg:   &config{Config: commitlint.Config{Gitmoji: true}},
+			title: "✨ Feat(pencil): add eraser",
+			want: &titleDiagnosis{
+				Problems:   []string{"the type `Feat` must be lowercase"},
+				Suggestion: "✨ feat(pencil): add eraser",
+			},
+		},
+		{
+			name:  "gitmoji without a type",
+			cfg:   &config{Config: commitlint.Config{Gitmoji: true}},
+			title: ":sparkles: Add eraser to pencil",
+			want: &titleDiagnosis{
+				Problems: []string{
+					"the type and description must be separated by a colon followed by a space (`: `)",
+					"the title must start with a type, e.g. `feat: `",
+				},
+				Suggestion: ":sparkles: feat: Add eraser to pencil",
+			},
+		},
+		{
+			name:  "missing required scope",
+			cfg:   &config{Config: commitlint.Config{Scopes: commitlint.ScopesConfig{Required: true}}},
+			title: "fix: stop graphite breaking",
+			want: &titleDiagnosis{
+				Problems:   []string{"commit scope is required"},
+				Suggestion: "fix: stop graphite breaking",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg == nil {
+				cfg = &config{}
+			}
+
+			assert.DeepEqual(t, diagnoseTitle(cfg, tt.title), tt.want)
+		})
+	}
+}
+
+func Test_renderFailureComment(t *testing.T) {
+	cfg, err := parseConfig([]byte(`types:
+  remove: [build, chore, ci, docs, perf, refactor, style, test]
+references:
+  types: [fix]
+  jira_projects: [DT]
+lint:
+  lowercase: true
+`))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		want  string
+	}{
+		{
+			name:  "invalid syntax",
+			title: "feat(pencil):add eraser",
+			want: commentMarker + `
+### :x: Pull request title failed the conventional commit check
+
+The title ` + "`feat(pencil):add eraser`" + ` failed validation: pr title does not match conventional commit syntax.
+
+What's wrong:
+
+- the colon after the type must be followed by exactly one space
+
+Suggested title:
+
+` + "```\nfeat(pencil): add eraser\n```" + `
+
+Allowed types: ` + "`feat`, `fix`, `revert`" + `. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.
+
+_This comment will be removed automatically once the title is fixed._
+`,
+		},
+		{
+			name:  "lint rule",
+			title: "feat: Add picker.",
+			want: commentMarker + `
+### :x: Pull request title failed the conventional commit check
+
+The title ` + "`feat: Add picker.`" + ` failed validation: description does not follow lint rule "lowercase": ` +
+				`description must start with a lowercase letter, use "add" instead of "Add".
+
+Allowed types: ` + "`feat`, `fix`, `revert`" + `. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.
+
+_This comment will be removed automatically once the title is fixed._
+`,
+		},
+		{
+			name:  "missing reference",
+			title: "fix: stop graphite breaking",
+			want: commentMarker + `
+### :x: Pull request title failed the conventional commit check
+
+The title ` + "`fix: stop graphite breaking`" + ` failed validation: commit type "fix" requires a reference to an issue ` +
+				`in the scope, description or branch name, accepted formats: GitHub issue (e.g. #123), Jira issue key (e.g. DT-123).
+
+What's wrong:
+
+- reference an issue in the scope, the description or the branch name, in one of the accepted formats: ` +
+				`GitHub issue (e.g. #123), Jira issue key (e.g. DT-123)
+
+Allowed types: ` + "`feat`, `fix`, `revert`" + `. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.
+
+_This comment will be removed automatically once the title is fixed._
+`,
+		},
+		{
+			name:  "revert without reference",
+			title: "revert: feat(pencil): add eraser",
+			want: commentMarker + `
+### :x: Pull request title failed the conventional commit check
+
+The title ` + "`revert: feat(pencil): add eraser`" + ` failed validation: revert must reference what it reverts with a ` +
+				`"This reverts commit <sha>." line, a "Refs: <sha>" footer or a "Reverts org/repo#<number>" line in the description.
+
+What's wrong:
+
+- a ` + "`revert`" + ` must reference the commits or the pull request it reverts in the description, with a ` +
+				"`This reverts commit <sha>.` line, a `Refs: <sha>` footer or a `Reverts org/repo#<number>` line" + `
+- the referenced commits must exist in this repository and the referenced pull requests must exist
+
+Allowed types: ` + "`feat`, `fix`, `revert`" + `. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.
+
+_This comment will be removed automatically once the title is fixed._
+`,
+		},
+		{
+			name:  "revert of a missing commit",
+			title: "revert: feat(pencil): add eraser",
+			body:  "This reverts commit 0123abc.",
+			want: commentMarker + `
+### :x: Pull request title failed the conventional commit check
+
+The title ` + "`revert: feat(pencil): add eraser`" + ` failed validation: reverted commit 0123abc does not exist in getoutreach/pencil.
+
+What's wrong:
+
+- a ` + "`revert`" + ` must reference the commits or the pull request it reverts in the description, with a ` +
+				"`This reverts commit <sha>.` line, a `Refs: <sha>` footer or a `Reverts org/repo#<number>` line" + `
+- the referenced commits must exist in this repository and the referenced pull requests must exist
+
+Allowed types: ` + "`feat`, `fix`, `revert`" + `. See https://www.conventionalcommits.org/en/v1.0.0/ for more information.
+
+_This comment will be removed automatically once the title is fixed._
+`,
+		},
+	}
+	// Every request 404s, so referenced commits and pull requests don't exist.
+	client := newTestClient(t, http.NewServeMux())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := newPR(tt.title, tt.body)
+			pr.Base.Repo.Owner.Login, pr.Base.Repo.Name = "getoutreach", "pencil"
+			pr.Head.Ref = "jdoe/graphite"
+
+			_, err := validatePullRequestTitle(context.Background(), client, cfg, pr)
+			assert.Assert(t, err != nil)
+			assert.Equal(t, renderFailureComment(cfg, tt.title, err), tt.want)
+		})
+	}
+}
+
+func Test_report_markdownTable(t *testing.T) {
+	rep := &report{
+		Title: "feat(pencil)!: drop ballpoint | gel support",
+		Commit: &conventional.Commit{
+			Type:     "feat",
+			Scope:    "pencil",
+			Breaking: true,
+		},
+		Commits: []commitResult{
+			{
+				SHA:     "b1e54856c1f1c615d1c4ea31dbebfc33362551f3",
+				Subject: "feat(pencil)!: drop ballpoint | gel support",
+				Commit:  &conventional.Commit{Type: "feat", Scope: "pencil", Breaking: true},
+			},
+			{
+				SHA:     "398f1ef4184001cbbc977fbd3bbd42a5b32c9280",
+				Subject: "oops",
+				Err:     errors.New("commit message does not match conventional commit syntax"),
+			},
+			{
+				SHA:     "1234",
+				Subject: "chore(deps): bump graphite",
+				Bypass:  "commit author email \"49699333+dependabot[bot]@users.noreply.github.com\" is in the bypass list",
+			},
+		},
+	}
+
+	want := `| | Message | Type | Scope | Breaking | Result |
+|---|---|---|---|---|---|
+| Title | feat(pencil)!: drop ballpoint \| gel support | feat | pencil | yes | :white_check_mark: passed |
+` + "| `b1e5485` | feat(pencil)!: drop ballpoint \\| gel support | feat | pencil | yes | :white_check_mark: passed |\n" +
+		"| `398f1ef` | oops |  |  |  | :x: commit message does not match conventional commit syntax |\n" +
+		"| `1234` | chore(deps): bump graphite |  |  |  | :fast_forward: bypassed: commit author email \"49699333+dependabot[bot]@users.noreply.github.com\" is in the bypass list |\n"
+
+	assert.Equal(t, rep.markdownTable(), want)
+	assert.Equal(t, rep.failures(), 1)
+}
+
+func Test_checkRunResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		reps       []*report
+		conclusion string
+		title      string
+	}{
+		{
+			name:       "passed",
+			reps:       []*report{{Number: 1}},
+			conclusion: "success",
+			title:      "Pull request follows the conventional commit format",
+		},
+		{
+			name:       "failed",
+			reps:       []*report{{Number: 1, TitleErr: errors.New("invalid")}},
+			conclusion: "failure",
+			title:      "1 conventional commit problem(s) found",
+		},
+		{
+			name:       "failed draft",
+			reps:       []*report{{Number: 1, TitleErr: errors.New("invalid"), WarnOnly: true}},
+			conclusion: "neutral",
+			title:      "1 conventional commit problem(s) found",
+		},
+		{
+			name:       "draft with a single commit not matching the title",
+			reps:       []*report{{Number: 1, SingleCommitErr: errors.New("mismatch"), WarnOnly: true}},
+			conclusion: "neutral",
+			title:      "1 conventional commit problem(s) found",
+		},
+		{
+			name:       "bypassed",
+			reps:       []*report{{Number: 1, Bypass: `pull request author "dependabot[bot]" is in BYPASS_LOGINS`}},
+			conclusion: "success",
+			title:      `Pull request bypassed the conventional commit check: pull request author "dependabot[bot]" is in BYPASS_LOGINS`,
+		},
+		{
+			name:       "merge group with a bypassed pull request",
+			reps:       []*report{{Number: 1, Bypass: "label"}, {Number: 2}},
+			conclusion: "success",
+			title:      "Pull request follows the conventional commit format",
+		},
+		{
+			name:       "merge group with a failed pull request",
+			reps:       []*report{{Number: 1, Bypass: "label"}, {Number: 2, SquashErr: errors.New("invalid")}},
+			conclusion: "failure",
+			title:      "1 conventional commit problem(s) found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conclusion, title := checkRunResult(tt.reps)
+			assert.Equal(t, conclusion, tt.conclusion)
+			assert.Equal(t, title, tt.title)
+		})
+	}
+}
+
+func Test_checkRunAnnotations(t *testing.T) {
+	annotation := func(level, title, message string) *github.CheckRunAnnotation {
+		return &github.CheckRunAnnotation{
+			Path:            github.Ptr(".github"),
+			StartLine:       github.Ptr(1),
+			EndLine:         github.Ptr(1),
+			AnnotationLevel: github.Ptr(level),
+			Title:           github.Ptr(title),
+			Message:         github.Ptr(message),
+		}
+	}
+
+	tests := []struct {
+		name string
+		reps []*report
+		want []*github.CheckRunAnnotation
+	}{
+		{
+			name: "passed",
+			reps: []*report{{Number: 1, Title: "feat: add eraser", Commits: []commitResult{{SHA: "398f1ef0", Subject: "feat: add eraser"}}}},
+		},
+		{
+			name: "failed title and commit",
+			reps: []*report{{
+				Number:   1,
+				Title:    "add eraser",
+				TitleErr: errors.New("invalid"),
+				Commits: []commitResult{
+					{SHA: "1234567890", Subject: "feat: add eraser"},
+					{SHA: "398f1ef0", Subject: "oops", Err: errors.New("commit message does not match conventional commit syntax")},
+				},
+			}},
+			want: []*github.CheckRunAnnotation{
+				annotation("failure", "Title of #1: add eraser", "invalid"),
+				annotation("failure", "Commit 398f1ef: oops", "commit message does not match conventional commit syntax"),
+			},
+		},
+		{
+			name: "failed draft",
+			reps: []*report{{Number: 1, Title: "add eraser", TitleErr: errors.New("invalid"), WarnOnly: true}},
+			want: []*github.CheckRunAnnotation{annotation("warning", "Title of #1: add eraser", "invalid")},
+		},
+		{
+			name: "merge group",
+			reps: []*report{
+				{Number: 1, Title: "add eraser", TitleErr: errors.New("invalid")},
+				{Number: 2, Title: "feat: add sharpener"},
+				{Number: 3, Title: "fix eraser", TitleErr: errors.New("invalid")},
+			},
+			want: []*github.CheckRunAnnotation{
+				annotation("failure", "Title of #1: add eraser", "invalid"),
+				annotation("failure", "Title of #3: fix eraser", "invalid"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, checkRunAnnotations(tt.reps), tt.want)
+		})
+	}
+}
+
+func Test_labelChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        labelsConfig
+		commit     *conventional.Commit
+		current    []string
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{
+			name:    "adds default labels",
+			commit:  &conventional.Commit{Type: "feat", Breaking: true},
+			current: []string{"needs-review"},
+			wantAdd: []string{"breaking-change", "feature"},
+		},
+		{
+			name:       "removes stale labels from a previous title",
+			commit:     &conventional.Commit{Type: "fix"},
+			current:    []string{"feature", "breaking-change", "needs-review"},
+			wantAdd:    []string{"bugfix"},
+			wantRemove: []string{"breaking-change", "feature"},
+		},
+		{
+			name: "configured mapping",
+			cfg: labelsConfig{
+				Types:    map[string]string{"feat": "enhancement", "docs": "documentation"},
+				Scopes:   map[string]string{"api": "area/api"},
+				Breaking: "major",
+			},
+			commit:     &conventional.Commit{Type: "docs", Scope: "api"},
+			current:    []string{"enhancement", "feature"},
+			wantAdd:    []string{"area/api", "documentation"},
+			wantRemove: []string{"enhancement"},
+		},
+		{
+			name:    "nothing to do",
+			commit:  &conventional.Commit{Type: "chore"},
+			current: []string{"needs-review"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, remove := labelChanges(&tt.cfg, tt.commit, tt.current)
+			assert.DeepEqual(t, add, tt.wantAdd)
+			assert.DeepEqual(t, remove, tt.wantRemove)
+		})
+	}
+}
+
+func Test_mergeGroupPullRequests(t *testing.T) {
+	mg := &gh.MergeGroup{}
+	mg.MergeGroup.HeadRef = "refs/heads/gh-readonly-queue/main/pr-1230-398f1ef4184001cbbc977fbd3bbd42a5b32c9280"
+
+	commits := []*github.RepositoryCommit{
+		newCommit("aaa", "feat(pencil): add eraser (#1228)\n\n* feat: add eraser"),
+		newCommit("bbb", "Merge pull request #1229 from getoutreach/eraser\n\nfix: eraser"),
+		newCommit("ccc", "fix(clerk): Remove '=' from clerkgenproto args (#1230)"),
+		newCommit("ddd", "chore: not from a pull request"),
+	}
+
+	assert.DeepEqual(t, mergeGroupPullRequests(mg, commits), []int{1228, 1229, 1230})
+}
+
+func Test_checkMergeGroupPullRequest_notPullRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/getoutreach/actions/pulls/{number}", http.NotFound)
+	client := newTestClient(t, mux)
+
+	// Issue references in rebased commit subjects, e.g. "fix: handle empty input (#42)",
+	// look like pull request numbers but must not fail the merge group.
+	rep, err := checkMergeGroupPullRequest(context.Background(), client, client, "getoutreach", "actions", 42)
+	assert.NilError(t, err)
+	assert.Assert(t, rep == nil)
+}
+
+func Test_pullRequestBypassReason(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/getoutreach/teams/{team}/memberships/{login}", func(w http.ResponseWriter, r *http.Request) {
+		states := map[string]string{"release-eng/jdoe": "active", "release-eng/pending": "pending", "fnd-dtss/dtss-dev": "active"}
+		state, ok := states[r.PathValue("team")+"/"+r.PathValue("login")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"state":%q}`, state)
+	})
+	mux.HandleFunc("GET /repos/getoutreach/actions/contents/.github/CODEOWNERS", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"file","content":"* @getoutreach/fnd-dtss @jaredallard\n"}`)
+	})
+	mux.HandleFunc("GET /repos/getoutreach/actions/issues/{number}/events", func(w http.ResponseWriter, r *http.Request) {
+		labelers := map[string][]string{
+			"1": {"jaredallard"},
+			"2": {"dtss-dev"},
+			"3": {"mallory"},
+			"4": {"jaredallard", "mallory"},
+		}
+
+		events := make([]string, 0, len(labelers[r.PathValue("number")]))
+		for _, login := range labelers[r.PathValue("number")] {
+			events = append(events, fmt.Sprintf(`{"event":"labeled","label":{"name":"skip-conventional-commit"},"actor":{"login":%q}}`, login))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(events, ","))
+	})
+	client := newTestClient(t, mux)
+
+	tests := []struct {
+		name   string
+		author string
+		number int
+		labels []string
+		want   string
+	}{
+		{
+			name:   "author in the bypass logins",
+			author: "renovate[bot]",
+			want:   `pull request author "renovate[bot]" is in BYPASS_LOGINS`,
+		},
+		{
+			name:   "author in a bypass team",
+			author: "jdoe",
+			want:   `pull request author "jdoe" is a member of team "getoutreach/release-eng"`,
+		},
+		{
+			name:   "pending member of a bypass team",
+			author: "pending",
+		},
+		{
+			name:   "label applied by a code owner",
+			author: "octocat",
+			number: 1,
+			labels: []string{"skip-conventional-commit"},
+			want:   `label "skip-conventional-commit" was applied by code owner "jaredallard"`,
+		},
+		{
+			name:   "label applied by a member of a code owner team",
+			author: "octocat",
+			number: 2,
+			labels: []string{"skip-conventional-commit"},
+			want:   `label "skip-conventional-commit" was applied by code owner "dtss-dev"`,
+		},
+		{
+			name:   "label applied by someone else",
+			author: "octocat",
+			number: 3,
+			labels: []string{"skip-conventional-commit"},
+		},
+		{
+			name:   "label re-applied by someone else",
+			author: "octocat",
+			number: 4,
+			labels: []string{"skip-conventional-commit"},
+		},
+		{
+			name:   "other label",
+			author: "octocat",
+			number: 1,
+			labels: []string{"documentation"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(bypassLoginsEnv, "renovate[bot]")
+			t.Setenv(bypassTeamsEnv, "getoutreach/release-eng")
+			t.Setenv(bypassLabelsEnv, "skip-conventional-commit")
+
+			pr := &gh.PullRequest{Number: tt.number}
+			pr.User.Login = tt.author
+			pr.Base.Ref = "main"
+			pr.Base.Repo.Name = "actions"
+			pr.Base.Repo.Owner.Login = "getoutreach"
+			for _, label := range tt.labels {
+				pr.Labels = append(pr.Labels, struct {
+					Name string `json:"name"`
+				}{Name: label})
+			}
+
+			got, err := pullRequestBypassReason(context.Background(), client, client, pr)
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func Test_parseCodeOwners(t *testing.T) {
+	contents := `# This is a comment.
+*                 @getoutreach/fnd-dtss @jaredallard
+/actions/         @getoutreach/fnd-dtss # trailing comment
+/docs/            docs@example.com @malept
+`
+
+	assert.DeepEqual(t, parseCodeOwners(contents), []string{"getoutreach/fnd-dtss", "jaredallard", "malept"})
+}
+
+func Test_validateSquashMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		pr     *gh.PullRequest
+		errMsg string
+	}{
+		{
+			name: "title only",
+			pr:   newPR("fix(clerk): Remove '=' from clerkgenproto args", ""),
+		},
+		{
+			name: "breaking marker with footer",
+			pr: newPR("feat(pencil)!: drop ballpoint support",
+				"Ballpoint pens are no longer supported.\r\n\r\nBREAKING CHANGE: ballpoint pens must be replaced"),
+		},
+		{
+			name: "breaking footer without marker",
+			pr: newPR("feat(pencil): drop ballpoint support",
+				"Ballpoint pens are no longer supported.\r\n\r\nBREAKING CHANGE: ballpoint pens must be replaced"),
+			errMsg: "squash commit message is inconsistent: the description has a BREAKING CHANGE footer " +
+				"but the title is missing the `!` breaking change marker",
+		},
+		{
+			name: "stray breaking change in body",
+			pr: newPR("feat(pencil)!: drop ballpoint support",
+				"BREAKING CHANGE: ballpoint pens must be replaced\r\n\r\n## Notes for your reviewers\r\n\r\nNone."),
+			errMsg: "squash commit message is inconsistent: the description contains \"BREAKING CHANGE:\" " +
+				"outside of the footers (last paragraph), move it there or remove it",
+		},
+		{
+			name:   "invalid title",
+			pr:     newPR("Drop ballpoint support", ""),
+			errMsg: "squash commit message: commit message does not match conventional commit syntax",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSquashMessage(&config{}, squashMessage(tt.pr))
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func Test_changelogPreview(t *testing.T) {
+	pr := &gh.PullRequest{Number: 1230, HTMLURL: "https://github.com/getoutreach/bootstrap/pull/1230"}
+
+	rep := &report{Commit: &conventional.Commit{Type: "fix", Scope: "clerk", Description: "Remove '=' from clerkgenproto args"}}
+	assert.Equal(t, changelogPreview(pr, rep), `## Changelog preview
+
+Once merged, this pull request will result in a **patch** release with the following release notes:
+
+### Bug Fixes
+
+* **clerk:** Remove '=' from clerkgenproto args ([#1230](https://github.com/getoutreach/bootstrap/pull/1230))
+`)
+
+	rep = &report{Commit: &conventional.Commit{Type: "chore", Description: "update dependencies"}}
+	assert.Equal(t, changelogPreview(pr, rep), `## Changelog preview
+
+This pull request will not produce a changelog entry, as `+"`chore`"+` changes are not included in release notes.
+`)
+
+	// When commits were validated, they produce the entries instead of the title.
+	rep = &report{
+		Commit: &conventional.Commit{Type: "feat", Description: "add eraser"},
+		Commits: []commitResult{
+			{SHA: "1111111", Commit: &conventional.Commit{Type: "chore", Description: "update dependencies"}},
+			{SHA: "2222222", Commit: &conventional.Commit{Type: "ci", Description: "cache modules"}},
+			{SHA: "3333333", Commit: &conventional.Commit{Type: "chore", Description: "update linters"}},
+		},
+	}
+	assert.Equal(t, changelogPreview(pr, rep), `## Changelog preview
+
+This pull request will not produce a changelog entry, as `+"`chore` and `ci`"+` changes are not included in release notes.
+`)
+}
+
+func Test_runHook(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "conventional_commit.yaml")
+	assert.NilError(t, os.WriteFile(cfgPath, []byte("types:\n  remove: [style]\nreferences:\n  types: [feat]\n  jira_projects: [ABC]\nlint:\n  lowercase: true\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		message  string
+		branch   string
+		wantCode int
+		stderr   string
+	}{
+		{
+			name:    "valid",
+			message: "fix(pencil): stop graphite breaking\n# Please enter the commit message for your changes.\n",
+		},
+		{
+			name:    "reference in branch",
+			message: "feat(pencil): add graphite width",
+			branch:  "jdoe/ABC-123-graphite",
+		},
+		{
+			name:    "verbose commit",
+			message: "fix: stop graphite breaking\n" + gitScissors + "\ndiff --git a/pencil.go b/pencil.go\n",
+		},
+		{
+			name:    "fixup",
+			message: "fixup! feat(pencil): add graphite width",
+		},
+		{
+			name:     "type removed by config",
+			message:  "style: reformat pencil",
+			wantCode: 1,
+			stderr: "- the type `style` is not in the list of allowed types\n" +
+				"suggested header: <type>: reformat pencil\n" +
+				"error: commit type \"style\" is not in the list of allowed commit types\n",
+		},
+		{
+			name:     "invalid syntax",
+			message:  "feat(pencil):add graphite width",
+			wantCode: 1,
+			stderr: "- the colon after the type must be followed by exactly one space\n" +
+				"suggested header: feat(pencil): add graphite width\n" +
+				"error: commit message does not match conventional commit syntax\n",
+		},
+		{
+			name:     "lint rule",
+			message:  "fix(pencil): Stop graphite breaking",
+			wantCode: 1,
+			stderr: "error: description does not follow lint rule \"lowercase\": " +
+				"description must start with a lowercase letter, use \"stop\" instead of \"Stop\"\n",
+		},
+		{
+			name:     "missing reference",
+			message:  "feat(pencil): add graphite width",
+			branch:   "jdoe/graphite",
+			wantCode: 1,
+			stderr: "error: commit type \"feat\" requires a reference to an issue in the scope, description or branch name, " +
+				"accepted formats: GitHub issue (e.g. #123), Jira issue key (e.g. ABC-123)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgPath := filepath.Join(dir, "COMMIT_EDITMSG")
+			assert.NilError(t, os.WriteFile(msgPath, []byte(tt.message), 0o600))
+
+			branch := tt.branch
+			if branch == "" {
+				branch = "main"
+			}
+
+			var stderr strings.Builder
+			code := runHook([]string{"-config", cfgPath, "-branch", branch, msgPath}, strings.NewReader(""), &stderr)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, stderr.String(), tt.stderr)
+		})
+	}
+}
+
+func Test_editSkipReason(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "payloads", "pull_request_edited.json"))
+	assert.NilError(t, err)
+
+	var payload map[string]interface{}
+	assert.NilError(t, json.Unmarshal(b, &payload))
+
+	edited, err := gh.ParsePullRequestEventPayload(payload)
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name     string
+		event    *gh.PullRequestEvent
+		skip     string
+		previous string
+	}{
+		{
+			name:     "title edited",
+			event:    edited,
+			previous: "Remove '=' from clerkgenproto args",
+		},
+		{
+			name:  "nothing relevant edited",
+			event: &gh.PullRequestEvent{Action: "edited"},
+			skip:  "the title, description and base branch were not edited",
+		},
+		{
+			name:  "synchronize",
+			event: &gh.PullRequestEvent{Action: "synchronize"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, editSkipReason(tt.event), tt.skip)
+			assert.Equal(t, previousTitle(tt.event), tt.previous)
+		})
+	}
+}
+
+func Test_skipCheckReason(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/getoutreach/actions/commits/{ref}/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("check_name"), "conventional-commit")
+		if r.PathValue("ref") == "none" {
+			fmt.Fprint(w, `{"total_count":0,"check_runs":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"total_count":1,"check_runs":[{"status":"completed","conclusion":%q}]}`, r.PathValue("ref"))
+	})
+	client := newTestClient(t, mux)
+
+	titleEdited := &gh.PullRequestEvent{Action: "edited"}
+	titleEdited.Changes.Title = &struct {
+		From string `json:"from"`
+	}{From: "oops"}
+
+	tests := []struct {
+		name         string
+		event        *gh.PullRequestEvent
+		checkRunName string
+		previous     string
+		want         string
+	}{
+		{
+			name:         "previous check passed",
+			event:        &gh.PullRequestEvent{Action: "edited"},
+			checkRunName: "conventional-commit",
+			previous:     "success",
+			want:         "the title, description and base branch were not edited",
+		},
+		{
+			name:         "previous check failed",
+			event:        &gh.PullRequestEvent{Action: "edited"},
+			checkRunName: "conventional-commit",
+			previous:     "failure",
+		},
+		{
+			name:         "no previous check",
+			event:        &gh.PullRequestEvent{Action: "edited"},
+			checkRunName: "conventional-commit",
+			previous:     "none",
+		},
+		{
+			name:     "check run not published",
+			event:    &gh.PullRequestEvent{Action: "edited"},
+			previous: "success",
+		},
+		{
+			name:         "title edited",
+			event:        titleEdited,
+			checkRunName: "conventional-commit",
+			previous:     "success",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(checkRunNameEnv, tt.checkRunName)
+
+			pr := newPR("fix(pencil): stop graphite breaking", "")
+			pr.Base.Repo.Name = "actions"
+			pr.Base.Repo.Owner.Login = "getoutreach"
+			// The fake API returns the conclusion named by the head SHA.
+			pr.Head.SHA = tt.previous
+
+			assert.Equal(t, skipCheckReason(context.Background(), client, tt.event, pr), tt.want)
+		})
+	}
+}
+
+func Test_titleFixedSummary(t *testing.T) {
+	got := titleFixedSummary("Remove '=' from clerkgenproto args", "fix(clerk): Remove '=' from clerkgenproto args",
+		errors.New("pr title does not match conventional commit syntax"))
+	assert.Equal(t, got, `## :white_check_mark: Title fixed
+
+The pull request title is now a valid conventional commit.
+
+| | Title | Result |
+|---|---|---|
+| Before | Remove '=' from clerkgenproto args | :x: pr title does not match conventional commit syntax |
+| After | fix(clerk): Remove '=' from clerkgenproto args | :white_check_mark: |
+`)
+}
+
+func Test_pullRequestPolicy(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "payloads", "pull_request.json"))
+	assert.NilError(t, err)
+
+	var payload map[string]interface{}
+	assert.NilError(t, json.Unmarshal(b, &payload))
+
+	draft, err := gh.ParsePullRequestPayload(payload)
+	assert.NilError(t, err)
+	assert.Assert(t, draft.Draft)
+
+	tests := []struct {
+		name   string
+		env    string
+		pr     *gh.PullRequest
+		action string
+		want   string
+		err    string
+	}{
+		{
+			name:   "default enforces drafts",
+			pr:     draft,
+			action: "opened",
+			want:   draftPolicyEnforce,
+		},
+		{
+			name:   "skip draft",
+			env:    "skip",
+			pr:     draft,
+			action: "synchronize",
+			want:   draftPolicySkip,
+		},
+		{
+			name:   "warn draft",
+			env:    " warn ",
+			pr:     draft,
+			action: "edited",
+			want:   draftPolicyWarn,
+		},
+		{
+			name:   "ready for review is enforced",
+			env:    "skip",
+			pr:     draft,
+			action: "ready_for_review",
+			want:   draftPolicyEnforce,
+		},
+		{
+			name:   "not a draft is enforced",
+			env:    "warn",
+			pr:     &gh.PullRequest{},
+			action: "opened",
+			want:   draftPolicyEnforce,
+		},
+		{
+			name: "invalid policy",
+			env:  "ignore",
+			pr:   draft,
+			err:  `DRAFT_POLICY must be one of "enforce", "warn" or "skip", got "ignore"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(draftPolicyEnv, tt.env)
+
+			policy, err := draftPolicy()
+			if tt.err != "" {
+				assert.Error(t, err, tt.err)
+				return
+			}
+			assert.NilError(t, err)
+
+			got := pullRequestPolicy(tt.pr, &gh.PullRequestEvent{Action: tt.action}, policy)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func Test_buildRelease(t *testing.T) {
+	rel, err := buildRelease(&config{}, "v1.2.3", "main", "https://github.com/getoutreach/pencil", []*github.RepositoryCommit{
+		newCommit("1111111aaaaaaa", "feat(eraser): add eraser (#12)"),
+		newCommit("2222222bbbbbbb", "fix: stop graphite breaking"),
+		newCommit("3333333ccccccc", "Update README.md"),
+		newCommit("4444444ddddddd", "chore: update dependencies (#13)"),
+	})
+	assert.NilError(t, err)
+
+	assert.Equal(t, rel.Bump, "minor")
+	assert.Equal(t, rel.NextVersion, "v1.3.0")
+	assert.DeepEqual(t, rel.Skipped, []string{"3333333ccccccc"})
+	assert.Equal(t, rel.Changelog.Markdown(), `### Features
+
+* **eraser:** add eraser ([#12](https://github.com/getoutreach/pencil/pull/12))
+
+### Bug Fixes
+
+* stop graphite breaking ([2222222](https://github.com/getoutreach/pencil/commit/2222222bbbbbbb))
+`)
+}
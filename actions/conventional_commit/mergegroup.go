@@ -0,0 +1,157 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for running the check on merge_group events,
+// which are sent when pull requests are queued in a merge queue.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// rePullRequestReference matches the pull request number that GitHub includes in the
+// messages of squash commits, e.g. "feat: add picker (#123)", and merge commits, e.g.
+// "Merge pull request #123 from org/branch".
+var rePullRequestReference = regexp.MustCompile(`(?:\(#(\d+)\)$|^Merge pull request #(\d+) )`)
+
+// runOnMergeGroup validates every pull request in the merge group that triggered this
+// action. The pull requests in the group are determined from the head ref of the group and
+// the messages of the commits the merge queue created on top of the base branch.
+func runOnMergeGroup(ctx context.Context, client *github.Client, actionCtx *actions.GitHubContext) error {
+	mg, err := gh.ParseMergeGroupPayload(actionCtx.Event)
+	if err != nil {
+		return errors.Wrap(err, "parse event payload")
+	}
+
+	org, repo := mg.Repository.Owner.Login, mg.Repository.Name
+
+	reps, err := checkMergeGroup(ctx, client, newTeamClient(ctx, client), mg)
+	if err != nil {
+		if name := checkRunName(); name != "" {
+			if err := publishErrorCheckRun(ctx, client, org, repo, mg.MergeGroup.HeadSHA, name, err); err != nil {
+				actions.Warningf("unable to publish check run: %v", err)
+			}
+		}
+		return err
+	}
+
+	if name := checkRunName(); name != "" {
+		if err := publishCheckRun(ctx, client, org, repo, mg.MergeGroup.HeadSHA, name, reps...); err != nil {
+			actions.Warningf("unable to publish check run: %v", err)
+		}
+	}
+
+	var failures []string
+	for _, rep := range reps {
+		if err := rep.err(); err != nil {
+			failures = append(failures, fmt.Sprintf("pull request #%d: %v", rep.Number, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// checkMergeGroup checks every pull request in the merge group. The returned error is only
+// set when the merge group could not be checked, validation failures are recorded in the
+// reports. teamClient is used to read team memberships, see newTeamClient.
+func checkMergeGroup(ctx context.Context, client, teamClient *github.Client, mg *gh.MergeGroup) ([]*report, error) {
+	org, repo := mg.Repository.Owner.Login, mg.Repository.Name
+
+	commits, err := gh.CompareAllCommits(ctx, client, org, repo, mg.MergeGroup.BaseSHA, mg.MergeGroup.HeadSHA)
+	if err != nil {
+		return nil, errors.Wrap(err, "list merge group commits")
+	}
+
+	numbers := mergeGroupPullRequests(mg, commits)
+	if len(numbers) == 0 {
+		return nil, errors.Errorf("unable to determine the pull requests in merge group %q", mg.MergeGroup.HeadRef)
+	}
+	actions.Infof("pull requests in merge group: %v", numbers)
+
+	reps := make([]*report, 0, len(numbers))
+	for _, number := range numbers {
+		rep, err := checkMergeGroupPullRequest(ctx, client, teamClient, org, repo, number)
+		if err != nil {
+			return nil, err
+		}
+
+		if rep == nil {
+			// Subjects of rebased commits can end with an issue reference that looks like
+			// the pull request number of a squash commit.
+			actions.Infof("#%d is not a pull request, skipping it", number)
+			continue
+		}
+		reps = append(reps, rep)
+	}
+
+	if len(reps) == 0 {
+		return nil, errors.Errorf("unable to determine the pull requests in merge group %q", mg.MergeGroup.HeadRef)
+	}
+	return reps, nil
+}
+
+// checkMergeGroupPullRequest fetches and checks a single pull request in a merge group. A
+// nil report is returned when number is not the number of a pull request.
+func checkMergeGroupPullRequest(ctx context.Context, client, teamClient *github.Client, org, repo string, number int) (*report, error) { //nolint:lll // Why: Function signature.
+	apiPR, res, err := client.PullRequests.Get(ctx, org, repo, number)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "get pull request #%d", number)
+	}
+
+	pr, err := gh.PullRequestFromAPI(apiPR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "convert pull request #%d", number)
+	}
+
+	rep, _, err := checkPullRequest(ctx, client, teamClient, pr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "check pull request #%d", number)
+	}
+	return rep, nil
+}
+
+// mergeGroupPullRequests returns the sorted, de-duplicated numbers of the pull requests in
+// a merge group.
+func mergeGroupPullRequests(mg *gh.MergeGroup, commits []*github.RepositoryCommit) []int {
+	found := make(map[int]struct{})
+	if number, ok := mg.HeadPullRequestNumber(); ok {
+		found[number] = struct{}{}
+	}
+
+	for _, commit := range commits {
+		matches := rePullRequestReference.FindStringSubmatch(commitSubject(commit.GetCommit().GetMessage()))
+		if matches == nil {
+			continue
+		}
+
+		for _, match := range matches[1:] {
+			if number, err := strconv.Atoi(match); err == nil {
+				found[number] = struct{}{}
+			}
+		}
+	}
+
+	numbers := make([]int, 0, len(found))
+	for number := range found {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
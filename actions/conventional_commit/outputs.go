@@ -0,0 +1,47 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains helpers for setting the outputs of the action so that
+// they can be consumed by downstream jobs.
+
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// setJSONOutput sets the action output with the given name to the JSON encoding of v.
+func setJSONOutput(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "marshal %q output", name)
+	}
+
+	actions.SetOutput(name, string(b))
+	return nil
+}
+
+// setCommitOutputs sets the individual outputs describing the parsed pull request, as
+// well as the semver_bump output that downstream release jobs can use to determine the
+// next version.
+func setCommitOutputs(rep *report) {
+	actions.SetOutput("type", rep.Commit.Type)
+	actions.SetOutput("scope", rep.Commit.Scope)
+	actions.SetOutput("breaking", strconv.FormatBool(rep.Commit.Breaking))
+	actions.SetOutput("description", rep.Commit.Description)
+	actions.SetOutput("semver_bump", semverBump(rep).String())
+}
+
+// semverBump returns the most significant semantic version bump across the pull request
+// and, when they were validated, all of its commits.
+func semverBump(rep *report) conventional.Bump {
+	all := []*conventional.Commit{rep.Commit}
+	for _, commit := range rep.parsedCommits() {
+		all = append(all, commit.Commit)
+	}
+	return conventional.MaxBump(all)
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the release mode of the action, which computes the next
+// semantic version and changelog from the commits in a release range.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// Constant block for the environment variables that configure the release mode.
+const (
+	// releaseBaseTagEnv is the environment variable that, when set, runs the action in
+	// release mode: instead of checking a pull request, the commits between this tag and
+	// releaseHeadRefEnv are used to compute the next version and changelog.
+	releaseBaseTagEnv = "RELEASE_BASE_TAG"
+
+	// releaseHeadRefEnv is the commit-ish the release is made from. Defaults to the SHA
+	// the workflow is running on.
+	releaseHeadRefEnv = "RELEASE_HEAD_REF"
+)
+
+// release is the result of the release mode.
+type release struct {
+	// BaseTag is the tag the release range starts at.
+	BaseTag string
+
+	// HeadRef is the commit-ish the release range ends at.
+	HeadRef string
+
+	// Bump is the semantic version bump of the release, e.g. "minor".
+	Bump string
+
+	// NextVersion is the version of the release. It is the same as BaseTag when Bump is
+	// "none".
+	NextVersion string
+
+	// Changelog groups the commits in the release range.
+	Changelog conventional.Changelog
+
+	// Skipped are the SHAs of commits that are not conventional commits, and therefore
+	// are neither in the changelog nor taken into account for the version.
+	Skipped []string
+}
+
+// releaseBaseTag returns the tag the release range starts at, or an empty string if the
+// release mode is disabled.
+func releaseBaseTag() string {
+	return strings.TrimSpace(os.Getenv(releaseBaseTagEnv))
+}
+
+// runRelease computes the next version and changelog of the commits between the release
+// base tag and head ref and sets them as outputs.
+func runRelease(ctx context.Context, client *github.Client, actionCtx *actions.GitHubContext, baseTag string) error {
+	org, repo, ok := strings.Cut(actionCtx.Repository, "/")
+	if !ok {
+		return fmt.Errorf("unable to determine repository from %q", actionCtx.Repository)
+	}
+
+	head := strings.TrimSpace(os.Getenv(releaseHeadRefEnv))
+	if head == "" {
+		head = actionCtx.SHA
+	}
+
+	commits, err := gh.CompareAllCommits(ctx, client, org, repo, baseTag, head)
+	if err != nil {
+		return errors.Wrap(err, "list release commits")
+	}
+
+	cfg, err := loadConfig(ctx, client, org, repo, head)
+	if err != nil {
+		return errors.Wrap(err, "load configuration")
+	}
+
+	rel, err := buildRelease(cfg, baseTag, head, fmt.Sprintf("https://github.com/%s/%s", org, repo), commits)
+	if err != nil {
+		return err
+	}
+
+	actions.Infof("%d commits since %s result in a %s release: %s", len(commits), baseTag, rel.Bump, rel.NextVersion)
+
+	actions.SetOutput("next_version", rel.NextVersion)
+	actions.SetOutput("semver_bump", rel.Bump)
+	actions.SetOutput("changelog", rel.Changelog.Markdown())
+	if err := setJSONOutput("changelog_json", rel.Changelog); err != nil {
+		return err
+	}
+
+	gh.AddStepSummary(releaseSummary(rel))
+	return nil
+}
+
+// buildRelease parses the commits in the release range, which is hosted at repoURL, into
+// a release. Commits that are not conventional commits are skipped.
+func buildRelease(cfg *config, baseTag, head, repoURL string, commits []*github.RepositoryCommit) (*release, error) {
+	parser, err := cfg.Parser()
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &release{BaseTag: baseTag, HeadRef: head}
+
+	var parsed []*conventional.Commit
+	for _, commit := range commits {
+		message, _ := conventional.NormalizeRevert(commit.GetCommit().GetMessage())
+
+		c, err := parser.Parse(message)
+		if err != nil {
+			actions.Warningf("skipping commit %s, it is not a conventional commit: %q", shortSHA(commit.GetSHA()),
+				commitSubject(message))
+			rel.Skipped = append(rel.Skipped, commit.GetSHA())
+			continue
+		}
+
+		link := &conventional.Link{Text: shortSHA(commit.GetSHA()), URL: commit.GetHTMLURL()}
+		if matches := rePullRequestSuffix.FindStringSubmatch(c.Description); matches != nil {
+			c.Description = strings.TrimSuffix(c.Description, matches[0])
+			link = &conventional.Link{Text: "#" + matches[1], URL: repoURL + "/pull/" + matches[1]}
+		}
+
+		rel.Changelog.Add(c, link)
+		parsed = append(parsed, c)
+	}
+
+	bump := conventional.MaxBump(parsed)
+	rel.Bump = bump.String()
+	if rel.NextVersion, err = bump.Next(baseTag); err != nil {
+		return nil, errors.Wrap(err, "compute next version")
+	}
+
+	return rel, nil
+}
+
+// releaseSummary renders the release as markdown for the job summary.
+func releaseSummary(rel *release) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Release %s\n\n", rel.NextVersion)
+
+	if rel.Bump == conventional.BumpNone.String() {
+		fmt.Fprintf(&b, "No releasable changes since %s.\n", rel.BaseTag)
+	} else {
+		fmt.Fprintf(&b, "Changes since %s result in a **%s** release.\n\n", rel.BaseTag, rel.Bump)
+		fmt.Fprint(&b, rel.Changelog.Markdown())
+	}
+
+	if len(rel.Skipped) > 0 {
+		fmt.Fprintf(&b, "\n%d commit(s) were skipped as they are not conventional commits.\n", len(rel.Skipped))
+	}
+	return b.String()
+}
@@ -0,0 +1,260 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the report of everything that was validated on a pull
+// request and helpers to render it.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+)
+
+// report contains the results of every validation performed on a pull request, so that
+// they can be surfaced in places like check runs and comments rather than only as an
+// error.
+type report struct {
+	// Number is the number of the pull request that was validated.
+	Number int
+
+	// Title is the pull request title that was validated.
+	Title string
+
+	// Bypass is the reason the whole pull request was allowed to bypass validation, if it
+	// was. Only Number and Title are set along with it in that case.
+	Bypass string
+
+	// Commit is the parsed pull request title and description, validated together as the
+	// message of the squash commit. This is nil if Bypass is set or if TitleErr is set
+	// because the title could not be parsed or validated as a commit message, but it is
+	// set along with TitleErr for missing issue references and invalid reverts.
+	Commit *conventional.Commit
+
+	// TitleErr is the reason the pull request title failed validation, if it did.
+	TitleErr error
+
+	// SingleCommitErr is set when the pull request has a single commit whose title does not
+	// match the pull request title.
+	SingleCommitErr error
+
+	// SquashMessage is the reconstructed squash commit message that was validated. This is
+	// empty if the squash commit message was not validated.
+	SquashMessage string
+
+	// SquashErr is the reason the squash commit message failed validation, if it did.
+	SquashErr error
+
+	// Commits are the results of validating each commit on the pull request. This is nil
+	// if commits were not validated.
+	Commits []commitResult
+
+	// CommitsErr summarizes every commit that failed validation, if any did.
+	CommitsErr error
+
+	// AutosquashErr names every commit that has to be squashed or reworded before merging,
+	// e.g. fixup! or WIP commits, if there are any.
+	AutosquashErr error
+
+	// WarnOnly denotes that failures are reported as warnings rather than failing the
+	// check, which is the case for draft pull requests with the draftPolicyWarn policy.
+	WarnOnly bool
+}
+
+// commitResult is the result of validating a single commit on a pull request.
+type commitResult struct {
+	// SHA is the SHA of the commit.
+	SHA string
+
+	// Subject is the first line of the commit message.
+	Subject string
+
+	// Commit is the parsed commit message. This is nil if Err is set or if the commit
+	// bypassed validation.
+	Commit *conventional.Commit
+
+	// Err is the reason the commit failed validation, if it did.
+	Err error
+
+	// Bypass is the reason the commit was allowed to bypass validation, if it was.
+	Bypass string
+}
+
+// parsedCommit is a commit on a pull request that has been successfully parsed.
+type parsedCommit struct {
+	SHA string `json:"sha"`
+	*conventional.Commit
+}
+
+// err returns an error describing every failed validation in the report, or nil if
+// everything passed.
+func (r *report) err() error {
+	var errs []string
+	for _, err := range []error{r.SingleCommitErr, r.TitleErr, r.SquashErr, r.CommitsErr, r.AutosquashErr} {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "\n"))
+}
+
+// failures returns the number of failed validations in the report.
+func (r *report) failures() int {
+	var n int
+	if r.TitleErr != nil {
+		n++
+	}
+
+	if r.SingleCommitErr != nil {
+		n++
+	}
+
+	if r.SquashErr != nil {
+		n++
+	}
+
+	if r.AutosquashErr != nil {
+		n++
+	}
+
+	for i := range r.Commits {
+		if r.Commits[i].Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// bypassedCommits returns the number of commits in the report that were allowed to bypass
+// validation.
+func (r *report) bypassedCommits() int {
+	var n int
+	for i := range r.Commits {
+		if r.Commits[i].Bypass != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// parsedCommits returns the commits in the report that were successfully parsed.
+func (r *report) parsedCommits() []parsedCommit {
+	parsed := make([]parsedCommit, 0, len(r.Commits))
+	for i := range r.Commits {
+		if r.Commits[i].Commit != nil {
+			parsed = append(parsed, parsedCommit{SHA: r.Commits[i].SHA, Commit: r.Commits[i].Commit})
+		}
+	}
+	return parsed
+}
+
+// markdownTable renders the report as a markdown table with a row for the pull request
+// title and each validated commit.
+func (r *report) markdownTable() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "| | Message | Type | Scope | Breaking | Result |")
+	fmt.Fprintln(&b, "|---|---|---|---|---|---|")
+	fmt.Fprintln(&b, markdownRow("Title", r.Title, r.Commit, r.TitleErr, r.Bypass))
+
+	if r.SquashMessage != "" {
+		fmt.Fprintln(&b, markdownRow("Squash", commitSubject(r.SquashMessage), nil, r.SquashErr, ""))
+	}
+
+	for i := range r.Commits {
+		c := &r.Commits[i]
+		fmt.Fprintln(&b, markdownRow("`"+shortSHA(c.SHA)+"`", c.Subject, c.Commit, c.Err, c.Bypass))
+	}
+
+	return b.String()
+}
+
+// markdownSummary renders the report for the job summary, calling out every bypass so that
+// they are auditable.
+func (r *report) markdownSummary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Conventional commit check for #%d\n\n", r.Number)
+
+	if r.Bypass != "" {
+		fmt.Fprintf(&b, "> [!WARNING]\n> This pull request bypassed the check: %s\n\n", r.Bypass)
+	}
+
+	for i := range r.Commits {
+		if c := &r.Commits[i]; c.Bypass != "" {
+			fmt.Fprintf(&b, "> [!WARNING]\n> Commit `%s` bypassed the check: %s\n\n", shortSHA(c.SHA), c.Bypass)
+		}
+	}
+
+	fmt.Fprintln(&b, r.markdownTable())
+	return b.String()
+}
+
+// markdownFailures renders the details of every failed validation in the report as
+// markdown.
+func (r *report) markdownFailures() string {
+	var b strings.Builder
+	if r.TitleErr != nil {
+		fmt.Fprintf(&b, "#### Title: %s\n\n%s\n\n", markdownEscape(r.Title), r.TitleErr.Error())
+	}
+
+	if r.SingleCommitErr != nil {
+		fmt.Fprintf(&b, "#### Single commit\n\n%s\n\n", r.SingleCommitErr.Error())
+	}
+
+	if r.SquashErr != nil {
+		fmt.Fprintf(&b, "#### Squash commit message\n\n%s\n\n```\n%s\n```\n\n", r.SquashErr.Error(), r.SquashMessage)
+	}
+
+	for i := range r.Commits {
+		c := &r.Commits[i]
+		if c.Err == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "#### Commit `%s`: %s\n\n%s\n\n", shortSHA(c.SHA), markdownEscape(c.Subject), c.Err.Error())
+	}
+
+	if r.AutosquashErr != nil {
+		fmt.Fprintf(&b, "#### Commits to squash\n\n%s\n\n", r.AutosquashErr.Error())
+	}
+
+	return b.String()
+}
+
+// markdownRow renders a single row of the table rendered by markdownTable.
+func markdownRow(ref, message string, commit *conventional.Commit, err error, bypass string) string {
+	var cType, scope, breaking string
+	if commit != nil {
+		cType, scope = commit.Type, commit.Scope
+		if commit.Breaking {
+			breaking = "yes"
+		}
+	}
+
+	result := ":white_check_mark: passed"
+	switch {
+	case bypass != "":
+		result = ":fast_forward: bypassed: " + markdownEscape(bypass)
+	case err != nil:
+		result = ":x: " + markdownEscape(err.Error())
+	}
+
+	return fmt.Sprintf("| %s | %s | %s | %s | %s | %s |", ref, markdownEscape(message), cType, scope, breaking, result)
+}
+
+// markdownEscape escapes characters in s that would otherwise break a markdown table.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// shortSHA returns the abbreviated form of a commit SHA.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for validating that revert pull requests
+// reference what they revert.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// Variable block for regular expression parsing.
+var (
+	// reRevertsPullRequest matches the description GitHub creates for pull requests made
+	// with the "Revert" button, e.g. "Reverts getoutreach/actions#123".
+	reRevertsPullRequest = regexp.MustCompile(`(?m)^Reverts ([\w.-]+)/([\w.-]+)#(\d+)\b`)
+
+	// rePullRequestSuffix matches the pull request number GitHub appends to the header of
+	// squash commits, e.g. "feat: add picker (#123)", capturing the number.
+	rePullRequestSuffix = regexp.MustCompile(`\s*\(#(\d+)\)$`)
+)
+
+// revertedPullRequest is a pull request referenced by a revert.
+type revertedPullRequest struct {
+	// Org is the owner of the repository the pull request is in.
+	Org string
+
+	// Repo is the name of the repository the pull request is in.
+	Repo string
+
+	// Number is the number of the pull request.
+	Number int
+}
+
+// revertedPullRequests returns the pull requests referenced with "Reverts org/repo#123" in
+// the body of a revert commit.
+func revertedPullRequests(commit *conventional.Commit) []revertedPullRequest {
+	var prs []revertedPullRequest
+	for _, matches := range reRevertsPullRequest.FindAllStringSubmatch(commit.Body, -1) {
+		number, err := strconv.Atoi(matches[3])
+		if err != nil {
+			continue
+		}
+		prs = append(prs, revertedPullRequest{Org: matches[1], Repo: matches[2], Number: number})
+	}
+	return prs
+}
+
+// revertError is returned by validateRevert when a revert does not reference what it
+// reverts, or references commits or pull requests that don't exist.
+type revertError struct {
+	// msg describes what is wrong with the references of the revert.
+	msg string
+}
+
+// Error implements error.
+func (e *revertError) Error() string {
+	return e.msg
+}
+
+// validateRevert checks that a revert commit references the commits or pull request it
+// reverts and that they exist in org/repo. Commits of other types are not checked.
+func validateRevert(ctx context.Context, client *github.Client, org, repo string, commit *conventional.Commit) error {
+	if commit.Type != conventional.RevertType {
+		return nil
+	}
+
+	shas := commit.RevertedSHAs()
+	prs := revertedPullRequests(commit)
+	if len(shas) == 0 && len(prs) == 0 {
+		return &revertError{msg: "revert must reference what it reverts with a \"This reverts commit <sha>.\" line, " +
+			"a \"Refs: <sha>\" footer or a \"Reverts org/repo#<number>\" line in the description"}
+	}
+
+	var originals []string
+	for _, sha := range shas {
+		reverted, res, err := client.Repositories.GetCommit(ctx, org, repo, sha, &github.ListOptions{})
+		if err != nil {
+			if res != nil && (res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusUnprocessableEntity) {
+				return &revertError{msg: fmt.Sprintf("reverted commit %s does not exist in %s/%s", sha, org, repo)}
+			}
+			return errors.Wrapf(err, "get reverted commit %s", sha)
+		}
+		originals = append(originals, commitSubject(reverted.GetCommit().GetMessage()))
+	}
+
+	for _, pr := range prs {
+		reverted, res, err := client.PullRequests.Get(ctx, pr.Org, pr.Repo, pr.Number)
+		if err != nil {
+			if res != nil && res.StatusCode == http.StatusNotFound {
+				return &revertError{msg: fmt.Sprintf("reverted pull request %s/%s#%d does not exist", pr.Org, pr.Repo, pr.Number)}
+			}
+			return errors.Wrapf(err, "get reverted pull request %s/%s#%d", pr.Org, pr.Repo, pr.Number)
+		}
+		originals = append(originals, reverted.GetTitle())
+	}
+
+	if len(originals) == 1 && !mirrorsHeader(commit.Description, originals[0]) {
+		actions.Warningf("revert description %q does not mirror the header of what it reverts %q", commit.Description, originals[0])
+	}
+	return nil
+}
+
+// mirrorsHeader returns true if description is the reverted header, ignoring the pull
+// request number GitHub appends to squash commits, e.g. " (#123)".
+func mirrorsHeader(description, reverted string) bool {
+	trim := func(s string) string {
+		return strings.TrimSpace(rePullRequestSuffix.ReplaceAllString(strings.TrimSpace(s), ""))
+	}
+	return trim(description) == trim(reverted)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for validating the commit message GitHub will
+// create when squash merging a pull request.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/getoutreach/actions/pkg/gh"
+	"github.com/pkg/errors"
+)
+
+// validateSquashMessageEnv is the environment variable that, when set to "true", enables
+// validating the commit message GitHub creates when squash merging with the "Pull request
+// title and description" default commit message.
+const validateSquashMessageEnv = "VALIDATE_SQUASH_MESSAGE"
+
+// validateSquashMessageEnabled returns true if validating the squash commit message is
+// enabled.
+func validateSquashMessageEnabled() bool {
+	return strings.TrimSpace(os.Getenv(validateSquashMessageEnv)) == "true"
+}
+
+// squashMessage reconstructs the commit message GitHub creates when squash merging the pull
+// request with the "Pull request title and description" default commit message.
+func squashMessage(pr *gh.PullRequest) string {
+	message := fmt.Sprintf("%s (#%d)", strings.TrimSpace(pr.Title), pr.Number)
+
+	if body := strings.TrimSpace(strings.ReplaceAll(pr.Body, "\r\n", "\n")); body != "" {
+		message += "\n\n" + body
+	}
+	return message
+}
+
+// validateSquashMessage validates the full squash commit message, including that any
+// breaking change footers in the description agree with the "!" marker in the title and
+// that "BREAKING CHANGE:" does not appear outside of the footers, where release tooling
+// would still pick it up.
+func validateSquashMessage(cfg *config, message string) error {
+	commit, err := validateCommitMessage(cfg, message)
+	if err != nil {
+		return errors.Wrap(err, "squash commit message")
+	}
+
+	var problems []string
+	for _, line := range strings.Split(commit.Body, "\n") {
+		token, _, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && conventional.IsBreakingChangeToken(token) {
+			problems = append(problems, fmt.Sprintf(
+				"the description contains %q outside of the footers (last paragraph), move it there or remove it", token+":"))
+			break
+		}
+	}
+
+	parser, err := cfg.Parser()
+	if err != nil {
+		return err
+	}
+
+	header, err := parser.ParseHeader(commit.Header)
+	if err != nil {
+		return errors.Wrap(err, "parse squash commit header")
+	}
+
+	breakingChanges := commit.BreakingChanges()
+	if len(breakingChanges) > 0 && !header.Breaking {
+		problems = append(problems,
+			"the description has a BREAKING CHANGE footer but the title is missing the `!` breaking change marker")
+	}
+
+	for _, change := range breakingChanges {
+		if strings.TrimSpace(change) == "" {
+			problems = append(problems, "the BREAKING CHANGE footer in the description must describe the breaking change")
+			break
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("squash commit message is inconsistent: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
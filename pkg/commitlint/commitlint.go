@@ -0,0 +1,103 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the validation of commit messages against a repository's
+// configuration.
+
+// Package commitlint validates that commit messages are conventional commits that follow
+// a repository's configuration. It is used by the conventional_commit action in CI as
+// well as locally as a commit-msg git hook, so that both report the same problems.
+package commitlint
+
+import (
+	"fmt"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+)
+
+// Variable block for the errors Validate returns, which can be matched with errors.Is to
+// tell which part of the commit message is invalid.
+var (
+	// ErrInvalidSyntax is returned by Validate when the commit message is not a
+	// conventional commit.
+	ErrInvalidSyntax = errors.New("commit message does not match conventional commit syntax")
+
+	// ErrTypeNotAllowed matches the errors Validate returns when the type of the commit is
+	// not allowed.
+	ErrTypeNotAllowed = errors.New("commit type is not allowed")
+
+	// ErrScopeNotAllowed matches the errors Validate returns when the scope of the commit
+	// is missing or not allowed.
+	ErrScopeNotAllowed = errors.New("commit scope is not allowed")
+
+	// ErrLint matches the errors Validate returns when the description does not follow
+	// one of the rules of LintConfig.
+	ErrLint = errors.New("description does not follow a lint rule")
+
+	// ErrMissingReference matches the errors ReferencesConfig.Validate returns when a
+	// commit does not reference an issue.
+	ErrMissingReference = errors.New("commit does not reference an issue")
+)
+
+// kindError is an error that keeps the message of err while matching kind with errors.Is.
+type kindError struct {
+	// kind is one of the errors of this package that the error matches.
+	kind error
+
+	// err is the error describing the problem.
+	err error
+}
+
+// Error implements error.
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error describing the problem.
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// Is returns true if target is the kind of the error.
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Validate parses the commit message and checks that it is a conventional commit whose
+// type, scope and description are allowed by this configuration. Revert headers created by
+// git and GitHub, e.g. `Revert "feat: add picker"`, are accepted as "revert" commits.
+//
+// Issue references are not checked since they may be in the branch name, see
+// ReferencesConfig.Validate.
+func (c *Config) Validate(message string) (*conventional.Commit, error) {
+	if normalized, ok := conventional.NormalizeRevert(message); ok {
+		message = normalized
+	}
+
+	parser, err := c.Parser()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := parser.Parse(message)
+	if err != nil {
+		return nil, ErrInvalidSyntax
+	}
+
+	if _, exists := c.AllowedTypes()[commit.Type]; !exists {
+		return nil, &kindError{
+			kind: ErrTypeNotAllowed,
+			err:  fmt.Errorf("commit type %q is not in the list of allowed commit types", commit.Type),
+		}
+	}
+
+	if err := c.ValidateScope(commit.Scope); err != nil {
+		return nil, &kindError{kind: ErrScopeNotAllowed, err: err}
+	}
+
+	if err := c.Lint.Check(commit); err != nil {
+		return nil, &kindError{kind: ErrLint, err: err}
+	}
+
+	return commit, nil
+}
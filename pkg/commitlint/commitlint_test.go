@@ -0,0 +1,285 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the tests for the commitlint package.
+
+package commitlint
+
+import (
+	"testing"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"gotest.tools/v3/assert"
+)
+
+func TestLintConfig_Check(t *testing.T) {
+	all := LintConfig{
+		MaxHeaderLength:      50,
+		MinDescriptionLength: 10,
+		Lowercase:            true,
+		NoTrailingPeriod:     true,
+		BannedWords:          []string{"WIP"},
+		Imperative:           true,
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		errMsg  string
+	}{
+		{
+			name:    "valid",
+			message: "fix(pencil): stop graphite breaking",
+		},
+		{
+			name:    "acronym",
+			message: "feat(pencil): API for graphite width",
+		},
+		{
+			name:    "header too long",
+			message: "fix(pencil): stop graphite breaking when too much pressure applied",
+			errMsg:  `description does not follow lint rule "max_header_length": header is 66 characters long, shorten it to at most 50 characters`,
+		},
+		{
+			name:    "description too short",
+			message: "fix: stuff",
+			errMsg:  `description does not follow lint rule "min_description_length": description is 5 characters long, describe the change in at least 10 characters`,
+		},
+		{
+			name:    "uppercase",
+			message: "fix(pencil): Stop graphite breaking",
+			errMsg:  `description does not follow lint rule "lowercase": description must start with a lowercase letter, use "stop" instead of "Stop"`,
+		},
+		{
+			name:    "trailing period",
+			message: "fix(pencil): stop graphite breaking.",
+			errMsg:  `description does not follow lint rule "no_trailing_period": description must not end with a period, remove the trailing "."`,
+		},
+		{
+			name:    "banned word",
+			message: "fix(pencil): stop graphite breaking (wip)",
+			errMsg:  `description does not follow lint rule "banned_words": description must not contain "wip", remove it before merging`,
+		},
+		{
+			name:    "past tense",
+			message: "fix(pencil): stopped graphite breaking",
+			errMsg:  `description does not follow lint rule "imperative": description must use the imperative mood, use "stop" instead of "stopped"`,
+		},
+		{
+			name:    "third person",
+			message: "feat(pencil): adds graphite width",
+			errMsg:  `description does not follow lint rule "imperative": description must use the imperative mood, use "add" instead of "adds"`,
+		},
+		{
+			name:    "gerund",
+			message: "chore: updating dependencies",
+			errMsg:  `description does not follow lint rule "imperative": description must use the imperative mood, use "update" instead of "updating"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := conventional.Parse(tt.message)
+			assert.NilError(t, err)
+
+			err = all.Check(commit)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestReferencesConfig_Validate(t *testing.T) {
+	jira, err := ParseConfig([]byte("references:\n  types: [feat, fix]\n  jira_projects: [ABC, DT]\n"))
+	assert.NilError(t, err)
+
+	github, err := ParseConfig([]byte("references:\n  types: [feat, fix]\n"))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name    string
+		cfg     *Config
+		message string
+		branch  string
+		errMsg  string
+	}{
+		{
+			name:    "type without requirement",
+			cfg:     jira,
+			message: "chore: update dependencies",
+		},
+		{
+			name:    "jira key in scope",
+			cfg:     jira,
+			message: "feat(ABC-123): add graphite width",
+		},
+		{
+			name:    "jira key in description",
+			cfg:     jira,
+			message: "fix: stop graphite breaking for DT-42",
+		},
+		{
+			name:    "github issue in footer",
+			cfg:     github,
+			message: "fix: stop graphite breaking\n\nCloses #42",
+		},
+		{
+			name:    "github issue in footer value",
+			cfg:     github,
+			message: "fix: stop graphite breaking\n\nRefs: getoutreach/pencil#42",
+		},
+		{
+			name:    "jira key in branch",
+			cfg:     jira,
+			message: "fix: stop graphite breaking",
+			branch:  "jdoe/DT-123-graphite",
+		},
+		{
+			name:    "number in footer",
+			cfg:     github,
+			message: "fix: stop graphite breaking\n\nReviewed-by: 42",
+			errMsg: "commit type \"fix\" requires a reference to an issue in the scope, description or branch name, " +
+				"accepted formats: GitHub issue (e.g. #123)",
+		},
+		{
+			name:    "words that look like jira keys",
+			cfg:     jira,
+			message: "feat: support UTF-8 and SHA-256\n\nTimestamps are formatted as ISO-8601.",
+			errMsg: "commit type \"feat\" requires a reference to an issue in the scope, description or branch name, " +
+				"accepted formats: GitHub issue (e.g. #123), Jira issue key (e.g. ABC-123)",
+		},
+		{
+			name:    "jira key without jira projects",
+			cfg:     github,
+			message: "feat(ABC-123): add graphite width",
+			errMsg: "commit type \"feat\" requires a reference to an issue in the scope, description or branch name, " +
+				"accepted formats: GitHub issue (e.g. #123)",
+		},
+		{
+			name:    "missing reference",
+			cfg:     jira,
+			message: "feat: add graphite width",
+			branch:  "jdoe/graphite",
+			errMsg: "commit type \"feat\" requires a reference to an issue in the scope, description or branch name, " +
+				"accepted formats: GitHub issue (e.g. #123), Jira issue key (e.g. ABC-123)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := conventional.Parse(tt.message)
+			assert.NilError(t, err)
+
+			err = tt.cfg.References.Validate(commit, tt.branch)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestConfig_ValidateScope_enforcePaths(t *testing.T) {
+	allowed := []ScopeConfig{
+		{Name: "api", Paths: []string{"services/api/**"}},
+		{Name: "web", Paths: []string{"services/web/**"}},
+		{Name: "deps"},
+	}
+	api := []string{"services/api/main.go"}
+	both := []string{"services/api/main.go", "services/web/index.ts"}
+
+	tests := []struct {
+		name          string
+		multipleAreas string
+		changedFiles  []string
+		header        string
+		errMsg        string
+	}{
+		{
+			name:         "matching area",
+			changedFiles: api,
+			header:       "fix(api): stop dropping requests",
+		},
+		{
+			name:         "scope without paths",
+			changedFiles: both,
+			header:       "chore(deps): bump graphite",
+		},
+		{
+			name:         "missing scope for single area",
+			changedFiles: api,
+			header:       "fix: stop dropping requests",
+			errMsg:       `the changes are in the "api" area, use it as the commit scope: (api)`,
+		},
+		{
+			name:         "wrong area",
+			changedFiles: api,
+			header:       "fix(web): stop dropping requests",
+			errMsg:       `the changes are in the "api" area, use it as the commit scope: (api)`,
+		},
+		{
+			name:         "no area changed",
+			changedFiles: []string{"README.md"},
+			header:       "docs: explain pencils",
+			errMsg:       "commit scope is required",
+		},
+		{
+			name:         "multiple areas with every scope",
+			changedFiles: both,
+			header:       "feat(web, api): add picker",
+		},
+		{
+			name:         "multiple areas without scope",
+			changedFiles: both,
+			header:       "feat: add picker",
+		},
+		{
+			name:         "multiple areas with one scope",
+			changedFiles: both,
+			header:       "feat(api): add picker",
+			errMsg:       "the changes span multiple areas (api, web), use all of them as the commit scope, (api,web), or omit it",
+		},
+		{
+			name:          "multiple areas requiring scopes",
+			multipleAreas: MultipleAreasScopes,
+			changedFiles:  both,
+			header:        "feat: add picker",
+			errMsg:        "the changes span multiple areas (api, web), use all of them as the commit scope: (api,web)",
+		},
+		{
+			name:          "multiple areas requiring no scope",
+			multipleAreas: MultipleAreasNone,
+			changedFiles:  both,
+			header:        "feat: add picker",
+		},
+		{
+			name:          "multiple scopes are not parsed when requiring no scope",
+			multipleAreas: MultipleAreasNone,
+			changedFiles:  both,
+			header:        "feat(api,web): add picker",
+			errMsg:        "commit message does not match conventional commit syntax",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Scopes: ScopesConfig{
+					Required:      true,
+					Allowed:       allowed,
+					EnforcePaths:  true,
+					MultipleAreas: tt.multipleAreas,
+				},
+				ChangedFiles: tt.changedFiles,
+			}
+			assert.NilError(t, cfg.Compile())
+
+			_, err := cfg.Validate(tt.header)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
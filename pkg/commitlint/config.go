@@ -0,0 +1,203 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the configuration that repositories can provide to
+// customize how commit messages are validated.
+
+package commitlint
+
+import (
+	"fmt"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTypes are the commit types that are allowed when a repository does not configure
+// its own.
+var DefaultTypes = map[string]struct{}{
+	"feat":     {},
+	"fix":      {},
+	"docs":     {},
+	"style":    {},
+	"refactor": {},
+	"perf":     {},
+	"test":     {},
+	"build":    {},
+	"ci":       {},
+	"chore":    {},
+	"revert":   {},
+}
+
+// Config is the repository level configuration for validating commit messages. The zero
+// value of this type is the default configuration. Configurations that are not created by
+// ParseConfig must be prepared with Compile before they are used.
+type Config struct {
+	// Types modifies the list of allowed commit types.
+	Types TypesConfig `yaml:"types"`
+
+	// Scopes restricts the scopes that are allowed to be used.
+	Scopes ScopesConfig `yaml:"scopes"`
+
+	// References requires commits of certain types to reference an issue.
+	References ReferencesConfig `yaml:"references"`
+
+	// Lint enables lint rules for the description of commits.
+	Lint LintConfig `yaml:"lint"`
+
+	// Gitmoji allows a gitmoji (https://gitmoji.dev) in front of the type or the
+	// description, e.g. "✨ feat: add picker" or "feat: ✨ add picker".
+	Gitmoji bool `yaml:"gitmoji"`
+
+	// ChangedFiles are the files changed by the commits being validated. This is used to
+	// enforce scopes that are restricted to certain paths.
+	ChangedFiles []string `yaml:"-"`
+}
+
+// TypesConfig modifies the default list of allowed commit types, DefaultTypes.
+type TypesConfig struct {
+	// Add are types that are allowed on top of DefaultTypes.
+	Add []string `yaml:"add"`
+
+	// Remove are types from DefaultTypes that are not allowed.
+	Remove []string `yaml:"remove"`
+}
+
+// ScopesConfig restricts the scopes that are allowed to be used.
+type ScopesConfig struct {
+	// Required denotes whether or not every commit must have a scope.
+	Required bool `yaml:"required"`
+
+	// Allowed is the allow-list of scopes. When empty, any scope is allowed.
+	Allowed []ScopeConfig `yaml:"allowed"`
+
+	// Pattern is the regular expression that scopes must match, e.g. `[-\w./]+` to allow
+	// dots or `[-\p{L}\p{N}_/]+` to allow any unicode letter. Defaults to
+	// conventional.DefaultScopePattern.
+	Pattern string `yaml:"pattern"`
+
+	// EnforcePaths requires the scope to match the areas, scopes in Allowed with Paths,
+	// that the changed files are in. Scopes in Allowed without Paths, e.g. "deps", are
+	// always allowed.
+	EnforcePaths bool `yaml:"enforce_paths"`
+
+	// MultipleAreas is what EnforcePaths requires when the changed files are in more than
+	// one area: MultipleAreasScopes, MultipleAreasNone or MultipleAreasEither (default).
+	MultipleAreas string `yaml:"multiple_areas"`
+}
+
+// ScopeConfig is a single entry in the scope allow-list.
+type ScopeConfig struct {
+	// Name is the scope as it appears in the commit header.
+	Name string `yaml:"name"`
+
+	// Paths, when not empty, are doublestar globs that at least one of the changed files
+	// must match for this scope to be allowed.
+	Paths []string `yaml:"paths"`
+}
+
+// ParseConfig parses and compiles a configuration file.
+func ParseConfig(b []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Compile(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Compile validates the configuration and compiles the patterns in it.
+func (c *Config) Compile() error {
+	if _, err := c.Parser(); err != nil {
+		return err
+	}
+
+	for _, scope := range c.Scopes.Allowed {
+		if scope.Name == "" {
+			return errors.New("scopes in the allow-list must have a name")
+		}
+
+		for _, pattern := range scope.Paths {
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("scope %q has invalid path pattern %q", scope.Name, pattern)
+			}
+		}
+	}
+
+	switch c.Scopes.MultipleAreas {
+	case "", MultipleAreasEither, MultipleAreasScopes, MultipleAreasNone:
+	default:
+		return fmt.Errorf("scopes.multiple_areas must be one of %q, %q or %q, got %q",
+			MultipleAreasEither, MultipleAreasScopes, MultipleAreasNone, c.Scopes.MultipleAreas)
+	}
+
+	if len(c.References.Types) > 0 {
+		if err := c.References.compile(); err != nil {
+			return err
+		}
+	}
+
+	if c.Lint.MaxHeaderLength < 0 || c.Lint.MinDescriptionLength < 0 {
+		return errors.New("lint lengths must not be negative")
+	}
+
+	return nil
+}
+
+// ScopePattern returns the regular expression that a single scope must match, Pattern or
+// conventional.DefaultScopePattern when it is not set.
+func (c *Config) ScopePattern() string {
+	if c.Scopes.Pattern == "" {
+		return conventional.DefaultScopePattern
+	}
+	return c.Scopes.Pattern
+}
+
+// Parser returns the parser for commit messages configured by this configuration.
+func (c *Config) Parser() (*conventional.Parser, error) {
+	pattern := c.ScopePattern()
+
+	if c.Scopes.EnforcePaths && c.Scopes.MultipleAreas != MultipleAreasNone {
+		// Allow a comma separated list of scopes, e.g. "(api,web)", for changes to multiple
+		// areas.
+		pattern = fmt.Sprintf(`(?:%s)(?:,\s?(?:%s))*`, pattern, pattern)
+	}
+
+	return conventional.NewParser(conventional.Options{
+		ScopePattern: pattern,
+		Gitmoji:      c.Gitmoji,
+	})
+}
+
+// AllowedTypes returns the commit types allowed by this configuration.
+func (c *Config) AllowedTypes() map[string]struct{} {
+	types := make(map[string]struct{}, len(DefaultTypes)+len(c.Types.Add))
+	for cType := range DefaultTypes {
+		types[cType] = struct{}{}
+	}
+
+	for _, cType := range c.Types.Add {
+		types[cType] = struct{}{}
+	}
+
+	for _, cType := range c.Types.Remove {
+		delete(types, cType)
+	}
+
+	return types
+}
+
+// NeedsChangedFiles returns true if this configuration requires ChangedFiles to be set in
+// order to validate scopes.
+func (c *Config) NeedsChangedFiles() bool {
+	for _, allowed := range c.Scopes.Allowed {
+		if len(allowed.Paths) != 0 {
+			return true
+		}
+	}
+	return false
+}
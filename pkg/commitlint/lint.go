@@ -0,0 +1,273 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the lint rules that can be enabled for the description
+// of a conventional commit, e.g. a maximum header length or the imperative mood.
+
+package commitlint
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+)
+
+// LintConfig enables lint rules for the description of commits. The zero value of this
+// type disables every rule.
+type LintConfig struct {
+	// MaxHeaderLength is the maximum number of characters in the header (the first line)
+	// of the commit, including the type and scope. Zero means no limit.
+	MaxHeaderLength int `yaml:"max_header_length"`
+
+	// MinDescriptionLength is the minimum number of characters in the description. Zero
+	// means no minimum.
+	MinDescriptionLength int `yaml:"min_description_length"`
+
+	// Lowercase requires the description to start with a lowercase letter. Descriptions
+	// starting with an acronym, e.g. "API", are allowed.
+	Lowercase bool `yaml:"lowercase"`
+
+	// NoTrailingPeriod disallows ending the description with a period.
+	NoTrailingPeriod bool `yaml:"no_trailing_period"`
+
+	// BannedWords are words, matched case insensitively, that are not allowed in the
+	// description, e.g. "WIP".
+	BannedWords []string `yaml:"banned_words"`
+
+	// Imperative requires the description to start with a verb in the imperative mood,
+	// e.g. "add" instead of "added" or "adds".
+	Imperative bool `yaml:"imperative"`
+}
+
+// lintRule is a single rule that the description of a commit must follow.
+type lintRule interface {
+	// name returns the name of the rule, as used in error messages.
+	name() string
+
+	// check returns an error explaining how to fix the commit if it does not follow the
+	// rule.
+	check(commit *conventional.Commit) error
+}
+
+// rules returns the lint rules enabled by this configuration, in the order they are
+// checked.
+func (c *LintConfig) rules() []lintRule {
+	var rules []lintRule
+	if c.MaxHeaderLength > 0 {
+		rules = append(rules, maxHeaderLengthRule(c.MaxHeaderLength))
+	}
+	if c.MinDescriptionLength > 0 {
+		rules = append(rules, minDescriptionLengthRule(c.MinDescriptionLength))
+	}
+	if c.Lowercase {
+		rules = append(rules, lowercaseRule{})
+	}
+	if c.NoTrailingPeriod {
+		rules = append(rules, noTrailingPeriodRule{})
+	}
+	if len(c.BannedWords) > 0 {
+		rules = append(rules, bannedWordsRule(c.BannedWords))
+	}
+	if c.Imperative {
+		rules = append(rules, imperativeRule{})
+	}
+	return rules
+}
+
+// Check checks the commit against every enabled rule and returns an error for the first
+// rule that it does not follow.
+func (c *LintConfig) Check(commit *conventional.Commit) error {
+	for _, rule := range c.rules() {
+		if err := rule.check(commit); err != nil {
+			return fmt.Errorf("description does not follow lint rule %q: %w", rule.name(), err)
+		}
+	}
+	return nil
+}
+
+// maxHeaderLengthRule limits the number of characters in the header of a commit.
+type maxHeaderLengthRule int
+
+// name implements lintRule.
+func (maxHeaderLengthRule) name() string {
+	return "max_header_length"
+}
+
+// check implements lintRule.
+func (r maxHeaderLengthRule) check(commit *conventional.Commit) error {
+	if length := utf8.RuneCountInString(commit.Header); length > int(r) {
+		return fmt.Errorf("header is %d characters long, shorten it to at most %d characters", length, int(r))
+	}
+	return nil
+}
+
+// minDescriptionLengthRule requires a minimum number of characters in the description of a
+// commit.
+type minDescriptionLengthRule int
+
+// name implements lintRule.
+func (minDescriptionLengthRule) name() string {
+	return "min_description_length"
+}
+
+// check implements lintRule.
+func (r minDescriptionLengthRule) check(commit *conventional.Commit) error {
+	if length := utf8.RuneCountInString(strings.TrimSpace(commit.Description)); length < int(r) {
+		return fmt.Errorf("description is %d characters long, describe the change in at least %d characters", length, int(r))
+	}
+	return nil
+}
+
+// lowercaseRule requires the description to start with a lowercase letter.
+type lowercaseRule struct{}
+
+// name implements lintRule.
+func (lowercaseRule) name() string {
+	return "lowercase"
+}
+
+// check implements lintRule.
+func (lowercaseRule) check(commit *conventional.Commit) error {
+	word := firstWord(commit.Description)
+	first, _ := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(first) || isAcronym(word) {
+		return nil
+	}
+
+	return fmt.Errorf("description must start with a lowercase letter, use %q instead of %q",
+		string(unicode.ToLower(first))+word[utf8.RuneLen(first):], word)
+}
+
+// noTrailingPeriodRule disallows ending the description with a period.
+type noTrailingPeriodRule struct{}
+
+// name implements lintRule.
+func (noTrailingPeriodRule) name() string {
+	return "no_trailing_period"
+}
+
+// check implements lintRule.
+func (noTrailingPeriodRule) check(commit *conventional.Commit) error {
+	if strings.HasSuffix(strings.TrimSpace(commit.Description), ".") {
+		return fmt.Errorf("description must not end with a period, remove the trailing %q", ".")
+	}
+	return nil
+}
+
+// bannedWordsRule disallows words in the description.
+type bannedWordsRule []string
+
+// name implements lintRule.
+func (bannedWordsRule) name() string {
+	return "banned_words"
+}
+
+// check implements lintRule.
+func (r bannedWordsRule) check(commit *conventional.Commit) error {
+	for _, word := range descriptionWords(commit.Description) {
+		for _, banned := range r {
+			if strings.EqualFold(word, banned) {
+				return fmt.Errorf("description must not contain %q, remove it before merging", word)
+			}
+		}
+	}
+	return nil
+}
+
+// imperativeRule requires the description to start with a verb in the imperative mood.
+type imperativeRule struct{}
+
+// name implements lintRule.
+func (imperativeRule) name() string {
+	return "imperative"
+}
+
+// check implements lintRule.
+func (imperativeRule) check(commit *conventional.Commit) error {
+	word := firstWord(commit.Description)
+	if verb, ok := imperativeVerb(strings.ToLower(word)); ok {
+		return fmt.Errorf("description must use the imperative mood, use %q instead of %q", verb, word)
+	}
+	return nil
+}
+
+// imperativeVerbs are common verbs at the start of commit descriptions. Only conjugations
+// of these verbs are reported by imperativeRule, to avoid false positives on words that
+// happen to end in "s", "ed" or "ing", e.g. "docs" or "embed".
+var imperativeVerbs = map[string]struct{}{
+	"add": {}, "allow": {}, "avoid": {}, "bump": {}, "change": {}, "clean": {}, "create": {},
+	"delete": {}, "deprecate": {}, "disable": {}, "document": {}, "drop": {}, "enable": {},
+	"ensure": {}, "expose": {}, "fix": {}, "handle": {}, "implement": {}, "improve": {},
+	"introduce": {}, "make": {}, "merge": {}, "migrate": {}, "move": {}, "prevent": {},
+	"refactor": {}, "release": {}, "remove": {}, "rename": {}, "replace": {}, "return": {},
+	"revert": {}, "set": {}, "simplify": {}, "skip": {}, "stop": {}, "support": {},
+	"switch": {}, "update": {}, "upgrade": {}, "use": {},
+}
+
+// imperativeVerb returns the imperative form of word if it is a conjugation, e.g. "added",
+// "adds" or "adding", of one of the imperativeVerbs.
+func imperativeVerb(word string) (string, bool) {
+	var stems []string
+	switch {
+	case strings.HasSuffix(word, "ing"):
+		stem := strings.TrimSuffix(word, "ing")
+		stems = append(stems, stem, stem+"e", undouble(stem))
+	case strings.HasSuffix(word, "ied"), strings.HasSuffix(word, "ies"):
+		stems = append(stems, word[:len(word)-3]+"y")
+	case strings.HasSuffix(word, "ed"):
+		stem := strings.TrimSuffix(word, "ed")
+		stems = append(stems, stem, stem+"e", undouble(stem))
+	case strings.HasSuffix(word, "es"):
+		stems = append(stems, strings.TrimSuffix(word, "es"), strings.TrimSuffix(word, "s"))
+	case strings.HasSuffix(word, "s"):
+		stems = append(stems, strings.TrimSuffix(word, "s"))
+	}
+
+	for _, stem := range stems {
+		if _, ok := imperativeVerbs[stem]; ok {
+			return stem, true
+		}
+	}
+	return "", false
+}
+
+// undouble removes the last letter of stem if it is a doubled consonant, e.g. "dropp"
+// becomes "drop".
+func undouble(stem string) string {
+	if n := len(stem); n >= 2 && stem[n-1] == stem[n-2] {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+// firstWord returns the first word of the description.
+func firstWord(description string) string {
+	if fields := strings.Fields(description); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// descriptionWords returns the words in the description, without surrounding punctuation.
+func descriptionWords(description string) []string {
+	return strings.FieldsFunc(description, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+}
+
+// isAcronym returns true if word is at least two characters long and does not contain any
+// lowercase letters, e.g. "API" or "CI".
+func isAcronym(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for requiring commits of certain
+// types to reference an issue or ticket.
+
+package commitlint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getoutreach/actions/pkg/conventional"
+	"github.com/pkg/errors"
+)
+
+// defaultReferencePatterns are the reference formats that are accepted when a repository
+// requires references without configuring its own patterns. Jira issue keys are only
+// accepted for the configured JiraProjects, see jiraReferencePattern, since a pattern for
+// any key also matches words like "UTF-8" or "SHA-256".
+var defaultReferencePatterns = []ReferencePattern{
+	{
+		Name:    "GitHub issue",
+		Pattern: `(?:\b[\w.-]+/[\w.-]+)?#\d+\b|https://github\.com/[\w.-]+/[\w.-]+/issues/\d+`,
+		Example: "#123",
+	},
+}
+
+// reJiraProject matches a valid Jira project key.
+var reJiraProject = regexp.MustCompile(`^[A-Z][A-Z0-9_]+$`)
+
+// ReferencesConfig requires commits of certain types to reference an issue or ticket in
+// the scope, the body or the branch name.
+type ReferencesConfig struct {
+	// Types are the commit types that require a reference, e.g. [feat, fix].
+	Types []string `yaml:"types"`
+
+	// JiraProjects are the keys of the Jira projects whose issue keys are accepted as
+	// references on top of defaultReferencePatterns, e.g. [DT] to accept "DT-123".
+	JiraProjects []string `yaml:"jira_projects"`
+
+	// Patterns are the accepted reference formats. Defaults to
+	// defaultReferencePatterns.
+	Patterns []ReferencePattern `yaml:"patterns"`
+}
+
+// ReferencePattern is a single accepted reference format.
+type ReferencePattern struct {
+	// Name is a human readable name for the format, e.g. "Jira issue key".
+	Name string `yaml:"name"`
+
+	// Pattern is the regular expression that matches a reference.
+	Pattern string `yaml:"pattern"`
+
+	// Example is an example reference shown in error messages, e.g. "ABC-123".
+	Example string `yaml:"example"`
+
+	// re is the compiled Pattern.
+	re *regexp.Regexp
+}
+
+// compile compiles the configured patterns, or the default ones if there are none. It must
+// be called before Validate.
+func (c *ReferencesConfig) compile() error {
+	if len(c.Patterns) == 0 {
+		c.Patterns = append([]ReferencePattern(nil), defaultReferencePatterns...)
+	}
+
+	if len(c.JiraProjects) > 0 {
+		jira, err := jiraReferencePattern(c.JiraProjects)
+		if err != nil {
+			return err
+		}
+		c.Patterns = append(c.Patterns, jira)
+	}
+
+	for i := range c.Patterns {
+		re, err := regexp.Compile(c.Patterns[i].Pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid reference pattern %q", c.Patterns[i].Pattern)
+		}
+		c.Patterns[i].re = re
+	}
+	return nil
+}
+
+// jiraReferencePattern returns the reference format of issue keys in the given Jira
+// projects.
+func jiraReferencePattern(projects []string) (ReferencePattern, error) {
+	keys := make([]string, 0, len(projects))
+	for _, project := range projects {
+		if !reJiraProject.MatchString(project) {
+			return ReferencePattern{}, fmt.Errorf("invalid Jira project key %q", project)
+		}
+		keys = append(keys, regexp.QuoteMeta(project))
+	}
+
+	return ReferencePattern{
+		Name:    "Jira issue key",
+		Pattern: `\b(?:` + strings.Join(keys, "|") + `)-\d+\b`,
+		Example: projects[0] + "-123",
+	}, nil
+}
+
+// required returns true if commits of the given type must contain a reference.
+func (c *ReferencesConfig) required(commitType string) bool {
+	for _, t := range c.Types {
+		if t == commitType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that commit contains a reference in its scope, description, body or
+// footers, or that branch does, when its type requires one.
+func (c *ReferencesConfig) Validate(commit *conventional.Commit, branch string) error {
+	if !c.required(commit.Type) {
+		return nil
+	}
+
+	sources := []string{commit.Scope, commit.Description, commit.Body, branch}
+	for _, footer := range commit.Footers {
+		// Footers are matched as written, since the "#" in footers like "Closes #123" is
+		// the separator between the token and the value.
+		sources = append(sources, footer.String())
+	}
+
+	for i := range c.Patterns {
+		for _, source := range sources {
+			if c.Patterns[i].re != nil && c.Patterns[i].re.MatchString(source) {
+				return nil
+			}
+		}
+	}
+
+	return &kindError{
+		kind: ErrMissingReference,
+		err: fmt.Errorf("commit type %q requires a reference to an issue in the scope, description or branch name, accepted formats: %s",
+			commit.Type, strings.Join(c.Formats(), ", ")),
+	}
+}
+
+// Formats describes the accepted reference formats for use in messages, e.g.
+// "GitHub issue (e.g. #123)".
+func (c *ReferencesConfig) Formats() []string {
+	formats := make([]string, 0, len(c.Patterns))
+	for i := range c.Patterns {
+		formats = append(formats, c.Patterns[i].format())
+	}
+	return formats
+}
+
+// format describes the pattern for use in error messages.
+func (p *ReferencePattern) format() string {
+	name := p.Name
+	if name == "" {
+		name = fmt.Sprintf("`%s`", p.Pattern)
+	}
+
+	if p.Example != "" {
+		return fmt.Sprintf("%s (e.g. %s)", name, p.Example)
+	}
+	return name
+}
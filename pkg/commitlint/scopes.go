@@ -0,0 +1,186 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the validation of commit scopes, including enforcing
+// that the scope matches the areas of a monorepo that the changed files are in.
+
+package commitlint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+)
+
+// Constant block for the values of ScopesConfig.MultipleAreas.
+const (
+	// MultipleAreasEither allows either every changed area as the scope or no scope at all.
+	MultipleAreasEither = "either"
+
+	// MultipleAreasScopes requires every changed area as the scope, e.g. "(api,web)".
+	MultipleAreasScopes = "scopes"
+
+	// MultipleAreasNone requires no scope at all.
+	MultipleAreasNone = "none"
+)
+
+// SplitScopes splits a scope into the comma separated scopes it lists, e.g. "api, web"
+// into "api" and "web". Nil is returned for an empty scope.
+func SplitScopes(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Split(scope, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// ValidateScope checks that the given scope, without surrounding parenthesis, is allowed
+// by this configuration.
+func (c *Config) ValidateScope(scope string) error {
+	scopes := SplitScopes(scope)
+
+	if c.Scopes.EnforcePaths {
+		if err := c.validateChangedAreas(scopes); err != nil {
+			return err
+		}
+	}
+
+	if len(scopes) == 0 {
+		// No scope is allowed for changes to multiple areas when enforcing paths, even
+		// when a scope is otherwise required.
+		multipleAreas := c.Scopes.EnforcePaths && len(c.ChangedAreas()) > 1
+		if c.Scopes.Required && !multipleAreas {
+			return errors.New("commit scope is required")
+		}
+		return nil
+	}
+
+	for _, s := range scopes {
+		if err := c.validateSingleScope(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSingleScope checks that a single scope is in the allow-list, if there is one,
+// and that the changed files match its paths.
+func (c *Config) validateSingleScope(scope string) error {
+	if len(c.Scopes.Allowed) == 0 {
+		return nil
+	}
+
+	if allowed := c.scope(scope); allowed != nil {
+		if len(allowed.Paths) == 0 || c.touchesPaths(allowed.Paths) {
+			return nil
+		}
+
+		return fmt.Errorf("commit scope %q is only allowed for changes to %s", scope, strings.Join(allowed.Paths, ", "))
+	}
+
+	names := make([]string, 0, len(c.Scopes.Allowed))
+	for _, allowed := range c.Scopes.Allowed {
+		names = append(names, allowed.Name)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("commit scope %q is not in the list of allowed scopes (%s)", scope, strings.Join(names, ", "))
+}
+
+// validateChangedAreas checks that scopes are the areas the changed files are in, as
+// required by ScopesConfig.EnforcePaths.
+func (c *Config) validateChangedAreas(scopes []string) error {
+	// Scopes without paths, e.g. "deps", are not tied to an area.
+	if len(scopes) == 1 {
+		if allowed := c.scope(scopes[0]); allowed != nil && len(allowed.Paths) == 0 {
+			return nil
+		}
+	}
+
+	areas := c.ChangedAreas()
+	switch {
+	case len(areas) == 0:
+		return nil
+	case len(areas) == 1:
+		if len(scopes) == 1 && scopes[0] == areas[0] {
+			return nil
+		}
+		return fmt.Errorf("the changes are in the %q area, use it as the commit scope: (%s)", areas[0], areas[0])
+	}
+
+	list := strings.Join(areas, ", ")
+	switch c.Scopes.MultipleAreas {
+	case MultipleAreasNone:
+		if len(scopes) > 0 {
+			return fmt.Errorf("the changes span multiple areas (%s), omit the commit scope", list)
+		}
+	case MultipleAreasScopes:
+		if !sameScopes(scopes, areas) {
+			return fmt.Errorf("the changes span multiple areas (%s), use all of them as the commit scope: (%s)",
+				list, strings.Join(areas, ","))
+		}
+	default:
+		if len(scopes) > 0 && !sameScopes(scopes, areas) {
+			return fmt.Errorf("the changes span multiple areas (%s), use all of them as the commit scope, (%s), or omit it",
+				list, strings.Join(areas, ","))
+		}
+	}
+	return nil
+}
+
+// ChangedAreas returns the names of the scopes in the allow-list whose paths match any of
+// the changed files, in the order they are configured.
+func (c *Config) ChangedAreas() []string {
+	var areas []string
+	for _, allowed := range c.Scopes.Allowed {
+		if len(allowed.Paths) != 0 && c.touchesPaths(allowed.Paths) {
+			areas = append(areas, allowed.Name)
+		}
+	}
+	return areas
+}
+
+// scope returns the entry in the allow-list with the given name, or nil if there is none.
+func (c *Config) scope(name string) *ScopeConfig {
+	for i := range c.Scopes.Allowed {
+		if c.Scopes.Allowed[i].Name == name {
+			return &c.Scopes.Allowed[i]
+		}
+	}
+	return nil
+}
+
+// touchesPaths returns true if any of the changed files match any of the given patterns.
+func (c *Config) touchesPaths(patterns []string) bool {
+	for _, file := range c.ChangedFiles {
+		for _, pattern := range patterns {
+			if ok, err := doublestar.Match(pattern, file); err == nil && ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameScopes returns true if a and b contain the same scopes, in any order.
+func sameScopes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		set[s] = struct{}{}
+	}
+
+	for _, s := range b {
+		if _, ok := set[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for determining the semantic version bump
+// a conventional commit results in.
+
+package conventional
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Bump is the semantic version increment that a commit results in.
+type Bump int
+
+// Constant block for the possible Bump values, ordered from least to most significant so
+// that they can be compared.
+const (
+	// BumpNone means the commit does not result in a new version.
+	BumpNone Bump = iota
+
+	// BumpPatch means the commit results in a new patch version.
+	BumpPatch
+
+	// BumpMinor means the commit results in a new minor version.
+	BumpMinor
+
+	// BumpMajor means the commit results in a new major version.
+	BumpMajor
+)
+
+// String returns the string representation of the bump, e.g. "minor".
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	case BumpNone:
+		return "none"
+	default:
+		return "none"
+	}
+}
+
+// Bump returns the semantic version bump this commit results in. Breaking changes are
+// major, "feat" is minor, "fix" and "perf" are patch, and everything else does not
+// result in a new version.
+func (c *Commit) Bump() Bump {
+	switch {
+	case c.Breaking:
+		return BumpMajor
+	case c.Type == "feat":
+		return BumpMinor
+	case c.Type == "fix", c.Type == "perf":
+		return BumpPatch
+	default:
+		return BumpNone
+	}
+}
+
+// MaxBump returns the most significant bump of all of the given commits.
+func MaxBump(commits []*Commit) Bump {
+	bump := BumpNone
+	for _, c := range commits {
+		if b := c.Bump(); b > bump {
+			bump = b
+		}
+	}
+	return bump
+}
+
+// reVersion matches a semantic version, optionally prefixed with "v", e.g. "v1.2.3" or
+// "1.2.3-rc.1+build.5".
+var reVersion = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// Next returns the version that results from applying the bump to version, keeping the
+// "v" prefix if there is one. Pre-release and build metadata are dropped, e.g. a patch
+// bump of "v1.2.3-rc.1" results in "v1.2.4". BumpNone returns version unchanged.
+func (b Bump) Next(version string) (string, error) {
+	matches := reVersion.FindStringSubmatch(version)
+	if matches == nil {
+		return "", fmt.Errorf("%q is not a semantic version", version)
+	}
+
+	if b == BumpNone {
+		return version, nil
+	}
+
+	parts := make([]int, 3)
+	for i := range parts {
+		n, err := strconv.Atoi(matches[i+2])
+		if err != nil {
+			return "", errors.Wrapf(err, "parse version %q", version)
+		}
+		parts[i] = n
+	}
+
+	switch b {
+	case BumpMajor:
+		parts = []int{parts[0] + 1, 0, 0}
+	case BumpMinor:
+		parts = []int{parts[0], parts[1] + 1, 0}
+	case BumpPatch, BumpNone:
+		parts[2]++
+	}
+
+	return fmt.Sprintf("%s%d.%d.%d", matches[1], parts[0], parts[1], parts[2]), nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for rendering a changelog from conventional
+// commits.
+
+package conventional
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constant block for the titles of the changelog sections.
+const (
+	// SectionBreakingChanges is the title of the changelog section for breaking changes.
+	SectionBreakingChanges = "Breaking Changes"
+
+	// SectionFeatures is the title of the changelog section for "feat" commits.
+	SectionFeatures = "Features"
+
+	// SectionBugFixes is the title of the changelog section for "fix" commits.
+	SectionBugFixes = "Bug Fixes"
+
+	// SectionPerformance is the title of the changelog section for "perf" commits.
+	SectionPerformance = "Performance Improvements"
+)
+
+// sectionOrder is the order sections are rendered in.
+var sectionOrder = []string{SectionBreakingChanges, SectionFeatures, SectionBugFixes, SectionPerformance}
+
+// typeSections maps commit types to the section they are listed in. Commit types that are
+// not in this map are not included in the changelog unless they are breaking changes.
+var typeSections = map[string]string{
+	"feat": SectionFeatures,
+	"fix":  SectionBugFixes,
+	"perf": SectionPerformance,
+}
+
+// Link is a reference from a changelog entry to where the change came from, e.g. a pull
+// request or a commit.
+type Link struct {
+	// Text is the text of the link, e.g. "#123".
+	Text string `json:"text"`
+
+	// URL is where the link points to.
+	URL string `json:"url"`
+}
+
+// Entry is a single line in a changelog.
+type Entry struct {
+	// Scope is the scope of the commit, if any.
+	Scope string `json:"scope,omitempty"`
+
+	// Description is the description of the change.
+	Description string `json:"description"`
+
+	// Link is where the change came from, if known.
+	Link *Link `json:"link,omitempty"`
+}
+
+// Section is a group of entries in a changelog, e.g. "Features".
+type Section struct {
+	// Title is the title of the section.
+	Title string `json:"title"`
+
+	// Entries are the changes in the section, in the order they were added.
+	Entries []Entry `json:"entries"`
+}
+
+// Changelog groups conventional commits into sections. The zero value is an empty
+// changelog ready to use.
+type Changelog struct {
+	// Sections are the non-empty sections of the changelog, in the order they should be
+	// rendered.
+	Sections []Section `json:"sections"`
+}
+
+// Add adds a commit to the changelog. Breaking changes are added to the breaking changes
+// section, described by their BREAKING CHANGE footer when there is one, in addition to the
+// section of their type.
+func (c *Changelog) Add(commit *Commit, link *Link) {
+	if commit.Breaking {
+		description := commit.Description
+		if changes := commit.BreakingChanges(); len(changes) > 0 {
+			description = strings.Join(changes, " ")
+		}
+		c.section(SectionBreakingChanges).add(Entry{Scope: commit.Scope, Description: description, Link: link})
+	}
+
+	if title, ok := typeSections[commit.Type]; ok {
+		c.section(title).add(Entry{Scope: commit.Scope, Description: commit.Description, Link: link})
+	}
+}
+
+// Empty returns true if no commits in the changelog resulted in an entry.
+func (c *Changelog) Empty() bool {
+	return len(c.Sections) == 0
+}
+
+// Markdown renders the changelog as markdown, with a level three heading for every section.
+func (c *Changelog) Markdown() string {
+	var b strings.Builder
+	for i := range c.Sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "### %s\n\n", c.Sections[i].Title)
+		for _, entry := range c.Sections[i].Entries {
+			b.WriteString("* " + entry.Markdown() + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Markdown renders the entry as a single line of markdown, e.g.
+// "**pencil:** add eraser ([#123](https://github.com/org/repo/pull/123))".
+func (e *Entry) Markdown() string {
+	var line string
+	if e.Scope != "" {
+		line = fmt.Sprintf("**%s:** ", e.Scope)
+	}
+	line += strings.ReplaceAll(e.Description, "\n", " ")
+
+	if e.Link != nil {
+		if e.Link.URL != "" {
+			line += fmt.Sprintf(" ([%s](%s))", e.Link.Text, e.Link.URL)
+		} else {
+			line += fmt.Sprintf(" (%s)", e.Link.Text)
+		}
+	}
+	return line
+}
+
+// section returns the section with the given title, creating it in the right position if
+// it does not exist yet.
+func (c *Changelog) section(title string) *Section {
+	for i := range c.Sections {
+		if c.Sections[i].Title == title {
+			return &c.Sections[i]
+		}
+	}
+
+	rank := func(title string) int {
+		for i := range sectionOrder {
+			if sectionOrder[i] == title {
+				return i
+			}
+		}
+		return len(sectionOrder)
+	}
+
+	i := 0
+	for i < len(c.Sections) && rank(c.Sections[i].Title) < rank(title) {
+		i++
+	}
+
+	c.Sections = append(c.Sections, Section{})
+	copy(c.Sections[i+1:], c.Sections[i:])
+	c.Sections[i] = Section{Title: title}
+	return &c.Sections[i]
+}
+
+// add appends an entry to the section.
+func (s *Section) add(entry Entry) {
+	s.Entries = append(s.Entries, entry)
+}
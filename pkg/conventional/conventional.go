@@ -0,0 +1,332 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the parser for conventional commit messages.
+
+// Package conventional parses commit messages that follow the Conventional Commits 1.0
+// specification (https://www.conventionalcommits.org/en/v1.0.0/) into their header, body
+// and footers.
+package conventional
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidHeader is returned when the header (first line) of a commit message does not
+// match the conventional commit syntax.
+var ErrInvalidHeader = errors.New("header does not match conventional commit syntax")
+
+// Constant block for footer tokens with special meaning.
+const (
+	// BreakingChangeToken is the footer token that denotes a breaking change.
+	BreakingChangeToken = "BREAKING CHANGE"
+
+	// BreakingChangeTokenAlt is the footer token that is synonymous with
+	// BreakingChangeToken.
+	BreakingChangeTokenAlt = "BREAKING-CHANGE"
+)
+
+// Constant block for the building blocks of the header regular expression.
+const (
+	// DefaultScopePattern is the regular expression that scopes, without the surrounding
+	// parenthesis, must match unless Options.ScopePattern is set.
+	DefaultScopePattern = `[-\w\/]+`
+
+	// GitmojiPattern matches a gitmoji, either as an emoji, e.g. "✨" or "⚡️", or as a
+	// shortcode, e.g. ":sparkles:". It is allowed in headers when Options.Gitmoji is set.
+	GitmojiPattern = `(?::[a-z0-9_+-]+:|\p{So}[\p{So}\p{Mn}\x{200D}]*)`
+)
+
+// defaultParser is the parser used by Parse and ParseHeader. Its header regular
+// expression is:
+//
+//	^(?P<type>\w+)(?P<scope>\((?:[-\w\/]+)\))?(?P<breaking>!)?:\s(?P<description>.*?)$
+//
+// For examples, see https://regex101.com/r/gkNDNK/1
+var defaultParser = func() *Parser {
+	p, err := NewParser(Options{})
+	if err != nil {
+		panic(err)
+	}
+	return p
+}()
+
+// Variable block for regular expression parsing.
+var (
+	// reFooter is a regular expression that matches the first line of a footer, which is a
+	// token followed by either ": " or " #" and then the start of the value.
+	reFooter = regexp.MustCompile(`^(?P<token>BREAKING CHANGE|[\w-]+)(?P<separator>:\s|\s#)(?P<value>.*)$`)
+
+	// reFooterToken stores the index of the token named capture group for reFooter.
+	reFooterToken = reFooter.SubexpIndex("token")
+
+	// reFooterSeparator stores the index of the separator named capture group for reFooter.
+	reFooterSeparator = reFooter.SubexpIndex("separator")
+
+	// reFooterValue stores the index of the value named capture group for reFooter.
+	reFooterValue = reFooter.SubexpIndex("value")
+)
+
+// Commit is a parsed conventional commit message.
+type Commit struct {
+	// Header is the first line of the commit message.
+	Header string `json:"header"`
+
+	// Type is the type of the commit, e.g. "feat".
+	Type string `json:"type"`
+
+	// Scope is the scope of the commit without the surrounding parenthesis, if any.
+	Scope string `json:"scope"`
+
+	// Breaking denotes whether the commit is a breaking change, either by a "!" in the
+	// header or a BREAKING CHANGE footer.
+	Breaking bool `json:"breaking"`
+
+	// Description is the text in the header after the ": " separator, without the
+	// gitmoji when one was parsed.
+	Description string `json:"description"`
+
+	// Gitmoji is the gitmoji in the header, e.g. "✨" or ":sparkles:", when parsed with
+	// Options.Gitmoji.
+	Gitmoji string `json:"gitmoji,omitempty"`
+
+	// Body is the free-form text between the header and the footers, if any.
+	Body string `json:"body"`
+
+	// Footers are the footers (trailers) at the end of the commit message, in the order
+	// they appear.
+	Footers []Footer `json:"footers"`
+}
+
+// Footer is a single footer of a commit message, e.g. "Refs: #123".
+type Footer struct {
+	// Token is the footer token, e.g. "Refs".
+	Token string `json:"token"`
+
+	// Separator is what separates the token from the value, either ": " or " #", e.g. in
+	// "Fixes #42".
+	Separator string `json:"separator"`
+
+	// Value is the footer value, which may span multiple lines.
+	Value string `json:"value"`
+}
+
+// String returns the footer as it was written in the commit message, e.g. "Fixes #42".
+func (f Footer) String() string {
+	return f.Token + f.Separator + f.Value
+}
+
+// Parse parses a full commit message, including the body and footers. ErrInvalidHeader is
+// returned if the first line of the message is not a conventional commit header.
+func Parse(message string) (*Commit, error) {
+	return defaultParser.Parse(message)
+}
+
+// ParseHeader parses only the header (first line) of a commit message. ErrInvalidHeader
+// is returned if header is not a conventional commit header.
+func ParseHeader(header string) (*Commit, error) {
+	return defaultParser.ParseHeader(header)
+}
+
+// Options configures how commit messages are parsed. The zero value parses messages as
+// described by the specification.
+type Options struct {
+	// ScopePattern is the regular expression that scopes, without the surrounding
+	// parenthesis, must match, e.g. `[-\w./]+` to allow dots. Defaults to
+	// DefaultScopePattern.
+	ScopePattern string
+
+	// Gitmoji allows a gitmoji (https://gitmoji.dev) in front of the type, e.g.
+	// "✨ feat: add picker", or in front of the description, e.g. "feat: ✨ add picker".
+	Gitmoji bool
+}
+
+// Parser parses commit messages according to its Options.
+type Parser struct {
+	// reHeader matches a valid conventional commit header.
+	reHeader *regexp.Regexp
+}
+
+// NewParser creates a Parser with the given options. An error is returned if
+// opts.ScopePattern is not a valid regular expression.
+func NewParser(opts Options) (*Parser, error) {
+	scope := opts.ScopePattern
+	if scope == "" {
+		scope = DefaultScopePattern
+	}
+
+	if _, err := regexp.Compile(scope); err != nil {
+		return nil, errors.Wrapf(err, "invalid scope pattern %q", scope)
+	}
+
+	var gitmojiType, gitmojiDescription string
+	if opts.Gitmoji {
+		gitmojiType = `(?:(?P<gitmoji>` + GitmojiPattern + `)\s+)?`
+		gitmojiDescription = `(?:(?P<descgitmoji>` + GitmojiPattern + `)\s+)?`
+	}
+
+	re, err := regexp.Compile(`^` + gitmojiType + `(?P<type>\w+)(?P<scope>\((?:` + scope + `)\))?(?P<breaking>!)?:\s` +
+		gitmojiDescription + `(?P<description>.*?)$`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid scope pattern %q", scope)
+	}
+	return &Parser{reHeader: re}, nil
+}
+
+// Parse parses a full commit message, including the body and footers. ErrInvalidHeader is
+// returned if the first line of the message is not a conventional commit header.
+func (p *Parser) Parse(message string) (*Commit, error) {
+	lines := strings.Split(normalizeNewlines(message), "\n")
+
+	commit, err := p.ParseHeader(lines[0])
+	if err != nil {
+		return nil, err
+	}
+
+	commit.Body, commit.Footers = parseBodyAndFooters(lines[1:])
+
+	for _, footer := range commit.Footers {
+		if IsBreakingChangeToken(footer.Token) {
+			commit.Breaking = true
+		}
+	}
+
+	return commit, nil
+}
+
+// ParseHeader parses only the header (first line) of a commit message. ErrInvalidHeader
+// is returned if header is not a conventional commit header.
+func (p *Parser) ParseHeader(header string) (*Commit, error) {
+	header = strings.TrimRight(header, "\r")
+
+	matches := p.reHeader.FindStringSubmatch(header)
+	if matches == nil {
+		return nil, ErrInvalidHeader
+	}
+
+	group := func(name string) string {
+		if i := p.reHeader.SubexpIndex(name); i >= 0 {
+			return matches[i]
+		}
+		return ""
+	}
+
+	gitmoji := group("gitmoji")
+	if gitmoji == "" {
+		gitmoji = group("descgitmoji")
+	}
+
+	return &Commit{
+		Header:      header,
+		Type:        group("type"),
+		Scope:       strings.TrimSuffix(strings.TrimPrefix(group("scope"), "("), ")"),
+		Breaking:    group("breaking") == "!",
+		Description: group("description"),
+		Gitmoji:     gitmoji,
+	}, nil
+}
+
+// IsBreakingChangeToken returns true if the given footer token denotes a breaking change.
+func IsBreakingChangeToken(token string) bool {
+	return token == BreakingChangeToken || token == BreakingChangeTokenAlt
+}
+
+// BreakingChanges returns the values of all breaking change footers on the commit.
+func (c *Commit) BreakingChanges() []string {
+	var changes []string
+	for _, footer := range c.Footers {
+		if IsBreakingChangeToken(footer.Token) {
+			changes = append(changes, footer.Value)
+		}
+	}
+	return changes
+}
+
+// FooterValues returns the values of all footers on the commit with the given token. The
+// comparison is case-insensitive.
+func (c *Commit) FooterValues(token string) []string {
+	var values []string
+	for _, footer := range c.Footers {
+		if strings.EqualFold(footer.Token, token) {
+			values = append(values, footer.Value)
+		}
+	}
+	return values
+}
+
+// parseBodyAndFooters splits the lines after the header into the body and the footers.
+// The footers are the trailing paragraphs of the message that each start with a footer
+// token.
+func parseBodyAndFooters(lines []string) (string, []Footer) {
+	paragraphs := splitParagraphs(lines)
+
+	footerStart := len(paragraphs)
+	for i := len(paragraphs) - 1; i >= 0; i-- {
+		if !reFooter.MatchString(paragraphs[i][0]) {
+			break
+		}
+		footerStart = i
+	}
+
+	body := make([]string, 0, footerStart)
+	for _, paragraph := range paragraphs[:footerStart] {
+		body = append(body, strings.Join(paragraph, "\n"))
+	}
+
+	var footers []Footer
+	for _, paragraph := range paragraphs[footerStart:] {
+		footers = append(footers, parseFooters(paragraph)...)
+	}
+
+	return strings.Join(body, "\n\n"), footers
+}
+
+// parseFooters parses the footers in a paragraph whose first line is known to start
+// with a footer token. Lines that do not start with a footer token are continuations of
+// the previous footer's value.
+func parseFooters(paragraph []string) []Footer {
+	var footers []Footer
+	for _, line := range paragraph {
+		if matches := reFooter.FindStringSubmatch(line); matches != nil {
+			footers = append(footers, Footer{
+				Token:     matches[reFooterToken],
+				Separator: matches[reFooterSeparator],
+				Value:     matches[reFooterValue],
+			})
+			continue
+		}
+
+		last := &footers[len(footers)-1]
+		last.Value += "\n" + line
+	}
+	return footers
+}
+
+// splitParagraphs groups lines into paragraphs separated by one or more blank lines.
+// Leading and trailing whitespace on each line is preserved, blank lines are dropped.
+func splitParagraphs(lines []string) [][]string {
+	var paragraphs [][]string
+	var current []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
+
+// normalizeNewlines converts all Windows style line endings to Unix style line endings.
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
@@ -0,0 +1,250 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+package conventional
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *Commit
+		err     error
+	}{
+		{
+			name:    "header only",
+			message: "fix(pencil): stop graphite breaking when too much pressure applied",
+			want: &Commit{
+				Header:      "fix(pencil): stop graphite breaking when too much pressure applied",
+				Type:        "fix",
+				Scope:       "pencil",
+				Description: "stop graphite breaking when too much pressure applied",
+			},
+		},
+		{
+			name:    "breaking marker",
+			message: "feat!: drop support for ballpoint pens",
+			want: &Commit{
+				Header:      "feat!: drop support for ballpoint pens",
+				Type:        "feat",
+				Breaking:    true,
+				Description: "drop support for ballpoint pens",
+			},
+		},
+		{
+			name: "multi-paragraph body and footers",
+			message: "fix: prevent racing of requests\r\n\r\n" +
+				"Introduce a request id and a reference to latest request.\r\n" +
+				"Dismiss incoming responses other than from latest request.\r\n\r\n" +
+				"Remove timeouts which were used to mitigate the racing issue but are\r\n" +
+				"obsolete now.\r\n\r\n" +
+				"Reviewed-by: Z\r\n" +
+				"Refs: #123\r\n",
+			want: &Commit{
+				Header:      "fix: prevent racing of requests",
+				Type:        "fix",
+				Description: "prevent racing of requests",
+				Body: "Introduce a request id and a reference to latest request.\n" +
+					"Dismiss incoming responses other than from latest request.\n\n" +
+					"Remove timeouts which were used to mitigate the racing issue but are\n" +
+					"obsolete now.",
+				Footers: []Footer{
+					{Token: "Reviewed-by", Separator: ": ", Value: "Z"},
+					{Token: "Refs", Separator: ": ", Value: "#123"},
+				},
+			},
+		},
+		{
+			name: "breaking change footer",
+			message: "feat: allow provided config object to extend other configs\n\n" +
+				"BREAKING CHANGE: `extends` key in config file is now used for extending other config files\n" +
+				"and must be a list.\n" +
+				"Fixes #42",
+			want: &Commit{
+				Header:      "feat: allow provided config object to extend other configs",
+				Type:        "feat",
+				Breaking:    true,
+				Description: "allow provided config object to extend other configs",
+				Footers: []Footer{
+					{
+						Token:     BreakingChangeToken,
+						Separator: ": ",
+						Value:     "`extends` key in config file is now used for extending other config files\nand must be a list.",
+					},
+					{Token: "Fixes", Separator: " #", Value: "42"},
+				},
+			},
+		},
+		{
+			name:    "hyphenated breaking change footer",
+			message: "refactor: rename eraser\n\nBREAKING-CHANGE: Eraser is now Rubber",
+			want: &Commit{
+				Header:      "refactor: rename eraser",
+				Type:        "refactor",
+				Breaking:    true,
+				Description: "rename eraser",
+				Footers: []Footer{
+					{Token: BreakingChangeTokenAlt, Separator: ": ", Value: "Eraser is now Rubber"},
+				},
+			},
+		},
+		{
+			name:    "footer-like line inside the body",
+			message: "docs: explain pressure\n\nNote: this is not a footer\nbecause the paragraph continues.\n\nThis is the end.",
+			want: &Commit{
+				Header:      "docs: explain pressure",
+				Type:        "docs",
+				Description: "explain pressure",
+				Body:        "Note: this is not a footer\nbecause the paragraph continues.\n\nThis is the end.",
+			},
+		},
+		{
+			name:    "invalid header",
+			message: "feat(pencil):add 'graphiteWidth' option",
+			err:     ErrInvalidHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.message)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestCommit_Bump(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Bump
+	}{
+		{header: "feat!: drop ballpoint pens", want: BumpMajor},
+		{header: "feat(pencil): add eraser", want: BumpMinor},
+		{header: "fix: stop graphite breaking", want: BumpPatch},
+		{header: "perf: sharpen faster", want: BumpPatch},
+		{header: "chore: update dependencies", want: BumpNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			commit, err := ParseHeader(tt.header)
+			assert.NilError(t, err)
+			assert.Equal(t, commit.Bump(), tt.want)
+		})
+	}
+}
+
+func TestChangelog_Markdown(t *testing.T) {
+	var changelog Changelog
+	for _, message := range []string{
+		"fix(pencil): stop graphite breaking",
+		"chore: update dependencies",
+		"feat(eraser): add eraser\n\nBREAKING CHANGE: pencils are now longer",
+		"feat: add sharpener",
+		"perf: sharpen faster",
+	} {
+		commit, err := Parse(message)
+		assert.NilError(t, err)
+		changelog.Add(commit, &Link{Text: "#123", URL: "https://github.com/getoutreach/actions/pull/123"})
+	}
+
+	want := `### Breaking Changes
+
+* **eraser:** pencils are now longer ([#123](https://github.com/getoutreach/actions/pull/123))
+
+### Features
+
+* **eraser:** add eraser ([#123](https://github.com/getoutreach/actions/pull/123))
+* add sharpener ([#123](https://github.com/getoutreach/actions/pull/123))
+
+### Bug Fixes
+
+* **pencil:** stop graphite breaking ([#123](https://github.com/getoutreach/actions/pull/123))
+
+### Performance Improvements
+
+* sharpen faster ([#123](https://github.com/getoutreach/actions/pull/123))
+`
+	assert.Equal(t, changelog.Markdown(), want)
+}
+
+func TestNormalizeRevert(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+		wantOK  bool
+		shas    []string
+	}{
+		{
+			name:    "git revert",
+			message: "Revert \"feat(pencil): add eraser\"\n\nThis reverts commit 4c2ab9e1d5f3a8b7c6d5e4f3a2b1c0d9e8f7a6b5.",
+			want:    "revert: feat(pencil): add eraser\n\nThis reverts commit 4c2ab9e1d5f3a8b7c6d5e4f3a2b1c0d9e8f7a6b5.",
+			wantOK:  true,
+			shas:    []string{"4c2ab9e1d5f3a8b7c6d5e4f3a2b1c0d9e8f7a6b5"},
+		},
+		{
+			name:    "github squashed revert",
+			message: "Revert \"feat(pencil): add eraser (#12)\" (#13)\n\nReverts getoutreach/pencil#12",
+			want:    "revert: feat(pencil): add eraser (#12) (#13)\n\nReverts getoutreach/pencil#12",
+			wantOK:  true,
+		},
+		{
+			name:    "conventional revert",
+			message: "revert: let us never again speak of the noodle incident\n\nRefs: 676104e, a215868",
+			want:    "revert: let us never again speak of the noodle incident\n\nRefs: 676104e, a215868",
+			shas:    []string{"676104e", "a215868"},
+		},
+		{
+			name:    "not a revert",
+			message: "fix: revert the pencil length",
+			want:    "fix: revert the pencil length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NormalizeRevert(tt.message)
+			assert.Equal(t, got, tt.want)
+			assert.Equal(t, ok, tt.wantOK)
+
+			commit, err := Parse(got)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, commit.RevertedSHAs(), tt.shas)
+		})
+	}
+}
+
+func TestBump_Next(t *testing.T) {
+	tests := []struct {
+		bump    Bump
+		version string
+		want    string
+		errMsg  string
+	}{
+		{bump: BumpNone, version: "v1.2.3", want: "v1.2.3"},
+		{bump: BumpPatch, version: "v1.2.3", want: "v1.2.4"},
+		{bump: BumpMinor, version: "1.2.3", want: "1.3.0"},
+		{bump: BumpMajor, version: "v1.2.3", want: "v2.0.0"},
+		{bump: BumpPatch, version: "v1.2.3-rc.1+build.5", want: "v1.2.4"},
+		{bump: BumpMinor, version: "release-1.2", errMsg: `"release-1.2" is not a semantic version`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bump.String()+" "+tt.version, func(t *testing.T) {
+			got, err := tt.bump.Next(tt.version)
+			if tt.errMsg != "" {
+				assert.Error(t, err, tt.errMsg)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains the logic for recognizing revert commits, both in the
+// conventional "revert:" form and in the form git and GitHub create by default.
+
+package conventional
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RevertType is the commit type of commits that revert other commits.
+const RevertType = "revert"
+
+// Variable block for regular expression parsing.
+var (
+	// reGitRevertHeader matches the header git and GitHub create when reverting a commit
+	// or pull request, e.g. `Revert "feat: add picker"`.
+	reGitRevertHeader = regexp.MustCompile(`^Revert "(.+)"(\s*\(#\d+\))?$`)
+
+	// reRevertsCommit matches the line git adds to the body when reverting a commit, e.g.
+	// "This reverts commit 4c2ab9e1d5...".
+	reRevertsCommit = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-f]{7,40})\b`)
+
+	// reSHA matches an abbreviated or full commit SHA.
+	reSHA = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// NormalizeRevert rewrites a message whose header was created by git or GitHub when
+// reverting, e.g. `Revert "feat: add picker"`, into a conventional revert commit, e.g.
+// `revert: feat: add picker`. The body and footers are kept as is. Messages that are not
+// in that form are returned unchanged along with false.
+func NormalizeRevert(message string) (string, bool) {
+	header, rest, _ := strings.Cut(normalizeNewlines(message), "\n")
+
+	matches := reGitRevertHeader.FindStringSubmatch(strings.TrimSpace(header))
+	if matches == nil {
+		return message, false
+	}
+
+	normalized := RevertType + ": " + matches[1] + matches[2]
+	if rest != "" {
+		normalized += "\n" + rest
+	}
+	return normalized, true
+}
+
+// RevertedSHAs returns the SHAs of the commits a revert commit reverts, read from "This
+// reverts commit <sha>." lines in the body and from "Refs" footers, as recommended by the
+// specification. Nil is returned for commits that are not of RevertType.
+func (c *Commit) RevertedSHAs() []string {
+	if c.Type != RevertType {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var shas []string
+	add := func(sha string) {
+		if _, ok := seen[sha]; !ok {
+			seen[sha] = struct{}{}
+			shas = append(shas, sha)
+		}
+	}
+
+	for _, matches := range reRevertsCommit.FindAllStringSubmatch(c.Body, -1) {
+		add(matches[1])
+	}
+
+	for _, value := range c.FooterValues("Refs") {
+		for _, ref := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if reSHA.MatchString(ref) {
+				add(ref)
+			}
+		}
+	}
+
+	return shas
+}
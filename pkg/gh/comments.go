@@ -0,0 +1,117 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains functions that help managing bot comments on issues
+// and pull requests through the GitHub API.
+
+package gh
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// CommentOnFailureEnv is the environment variable that, when set to "true", enables
+// actions to comment on pull requests explaining why their check failed. The comment is
+// updated on subsequent failures and deleted once the check passes.
+const CommentOnFailureEnv = "COMMENT_ON_FAILURE"
+
+// CommentOnFailure returns true if commenting on pull requests that fail a check is
+// enabled through CommentOnFailureEnv.
+func CommentOnFailure() bool {
+	return strings.TrimSpace(os.Getenv(CommentOnFailureEnv)) == "true"
+}
+
+// FindIssueComment returns the first comment on the given issue or pull request whose
+// body contains marker, or nil if there is no such comment. The marker is usually an HTML
+// comment (e.g. "<!-- my_action -->") that is included in the body of comments created by
+// an action so they can be found again later.
+func FindIssueComment(ctx context.Context, client *github.Client, org, repo string, number int, marker string) (*github.IssueComment, error) { //nolint:lll // Why: Function signature.
+	commentPage := 1
+	commentsPerPage := 100
+
+	for commentPage != 0 {
+		comments, res, err := client.Issues.ListComments(ctx, org, repo, number, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    commentPage,
+				PerPage: commentsPerPage,
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "list comments on #%d", number)
+		}
+
+		for i := range comments {
+			if strings.Contains(comments[i].GetBody(), marker) {
+				return comments[i], nil
+			}
+		}
+
+		commentPage = res.NextPage
+	}
+
+	return nil, nil
+}
+
+// UpsertIssueComment creates a comment with the given body on the given issue or pull
+// request, or updates the existing comment containing marker if there is one, so that
+// repeated runs of an action do not create duplicate comments. The marker is prepended to
+// body if body does not already contain it.
+func UpsertIssueComment(ctx context.Context, client *github.Client, org, repo string, number int, marker, body string) error {
+	if !strings.Contains(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	existing, err := FindIssueComment(ctx, client, org, repo, number, marker)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		if _, _, err := client.Issues.CreateComment(ctx, org, repo, number, &github.IssueComment{Body: &body}); err != nil {
+			return errors.Wrapf(err, "create comment on #%d", number)
+		}
+		return nil
+	}
+
+	if existing.GetBody() == body {
+		// Nothing has changed, avoid the unnecessary update.
+		return nil
+	}
+
+	if _, _, err := client.Issues.EditComment(ctx, org, repo, existing.GetID(), &github.IssueComment{Body: &body}); err != nil {
+		return errors.Wrapf(err, "edit comment %d on #%d", existing.GetID(), number)
+	}
+	return nil
+}
+
+// DeleteIssueComment deletes the comment containing marker on the given issue or pull
+// request, if there is one.
+func DeleteIssueComment(ctx context.Context, client *github.Client, org, repo string, number int, marker string) error {
+	existing, err := FindIssueComment(ctx, client, org, repo, number, marker)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return nil
+	}
+
+	if _, err := client.Issues.DeleteComment(ctx, org, repo, existing.GetID()); err != nil {
+		return errors.Wrapf(err, "delete comment %d on #%d", existing.GetID(), number)
+	}
+	return nil
+}
+
+// UpdateIssueComment creates or updates the comment containing marker on the given issue or
+// pull request with body, see UpsertIssueComment, or deletes it if body is empty. This keeps
+// a single comment explaining a failure in sync with the latest result of a check.
+func UpdateIssueComment(ctx context.Context, client *github.Client, org, repo string, number int, marker, body string) error {
+	if body == "" {
+		return DeleteIssueComment(ctx, client, org, repo, number, marker)
+	}
+	return UpsertIssueComment(ctx, client, org, repo, number, marker, body)
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains functions that help interacting with commits through
+// the GitHub API.
+
+package gh
+
+import (
+	"context"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// CompareAllCommits lists all commits that are reachable from head but not from base for a
+// given org/repo, paginating through the results. Base and head can be any commit-ish,
+// e.g. a SHA, branch or tag.
+func CompareAllCommits(ctx context.Context, client *github.Client, org, repo, base, head string) ([]*github.RepositoryCommit, error) {
+	commitPage := 1
+	commitsPerPage := 100
+
+	var commits []*github.RepositoryCommit
+	for commitPage != 0 {
+		comparison, res, err := client.Repositories.CompareCommits(ctx, org, repo, base, head, &github.ListOptions{
+			Page:    commitPage,
+			PerPage: commitsPerPage,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "compare %s...%s", base, head)
+		}
+
+		commits = append(commits, comparison.Commits...)
+		commitPage = res.NextPage
+	}
+
+	return commits, nil
+}
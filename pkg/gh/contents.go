@@ -0,0 +1,44 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains functions that help reading the contents of files
+// in a repository through the GitHub API.
+
+package gh
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v75/github"
+	"github.com/pkg/errors"
+)
+
+// ErrFileNotFound is returned by GetFileContents when the requested file does not exist
+// in the repository at the requested ref.
+var ErrFileNotFound = errors.New("file not found")
+
+// GetFileContents returns the decoded contents of the file at path in the given org/repo
+// at ref. If ref is empty the default branch of the repository is used. If the file does
+// not exist ErrFileNotFound is returned.
+func GetFileContents(ctx context.Context, client *github.Client, org, repo, path, ref string) ([]byte, error) {
+	file, _, res, err := client.Repositories.GetContents(ctx, org, repo, path, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	})
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, errors.Wrapf(err, "get contents of %q", path)
+	}
+
+	if file == nil {
+		return nil, errors.Errorf("%q is not a file", path)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode contents of %q", path)
+	}
+
+	return []byte(content), nil
+}
@@ -7,7 +7,10 @@ package gh
 
 import (
 	"encoding/json"
+	"regexp"
+	"strconv"
 
+	"github.com/google/go-github/v75/github"
 	"github.com/pkg/errors"
 )
 
@@ -18,16 +21,25 @@ import (
 // test/payloads/pull_request.json
 type PullRequest struct {
 	Title   string `json:"title"`
+	Body    string `json:"body"`
 	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
 	Commits int    `json:"commits"`
-	Head    struct {
+	Draft   bool   `json:"draft"` // Whether or not the pull request is a draft
+	User    struct {
+		Login string `json:"login"` // Login of the pull request author
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Head struct {
 		Ref string `json:"ref"` // HEAD branch name
 		SHA string `json:"sha"` // SHA of last commit on HEAD
 	} `json:"head"`
 	Base struct {
+		Ref  string `json:"ref"` // Base branch name
 		Repo struct {
 			Name  string `json:"name"` // Repository name
-			Ref   string `json:"ref"`  // Base branch name
 			Owner struct {
 				Login string `json:"login"` // Owner (organization/user) name
 			} `json:"owner"`
@@ -36,7 +48,9 @@ type PullRequest struct {
 }
 
 // ParsePullRequestPayload takes a GitHub actions payload and returns a *PullRequest
-// type with the fields from the payload marshaled into the type.
+// type with the fields from the payload marshaled into the type. The pull_request_target
+// event has the same payload as the pull_request event, so this function can be used for
+// both.
 func ParsePullRequestPayload(payload map[string]interface{}) (*PullRequest, error) {
 	eventMap, ok := payload["pull_request"].(map[string]interface{})
 	if !ok {
@@ -56,6 +70,121 @@ func ParsePullRequestPayload(payload map[string]interface{}) (*PullRequest, erro
 	return &event, nil
 }
 
+// PullRequestFromAPI converts a pull request returned by the GitHub API into a
+// *PullRequest, which is useful for running the same logic on pull requests that were
+// not part of the event payload. The pull request object in the pull_request event
+// payload has the same shape as the one returned by the API.
+func PullRequestFromAPI(pr *github.PullRequest) (*PullRequest, error) {
+	b, err := json.Marshal(pr)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal pull request into bytes")
+	}
+
+	var event PullRequest
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, errors.Wrap(err, "unmarshal pull request into concrete type")
+	}
+
+	return &event, nil
+}
+
+// PullRequestEvent is a type meant for the fields of a pull_request payload that describe
+// what happened to the pull request, as opposed to the pull request itself (see
+// PullRequest), to be marshaled into. This type can be extended with fields as they
+// become necessary in actions.
+//
+// An example of all the fields that could be added to this type can be found in
+// test/payloads/pull_request_edited.json
+type PullRequestEvent struct {
+	Action  string `json:"action"` // e.g. opened, edited, synchronize or ready_for_review
+	Changes struct {
+		Title *struct {
+			From string `json:"from"` // Previous title, only set when the title was edited
+		} `json:"title"`
+		Body *struct {
+			From string `json:"from"` // Previous body, only set when the body was edited
+		} `json:"body"`
+		Base *struct {
+			Ref struct {
+				From string `json:"from"` // Previous base branch name
+			} `json:"ref"`
+		} `json:"base"` // Only set when the base branch was changed
+	} `json:"changes"`
+}
+
+// ParsePullRequestEventPayload takes a GitHub actions payload and returns a
+// *PullRequestEvent type with the fields from the payload marshaled into the type.
+func ParsePullRequestEventPayload(payload map[string]interface{}) (*PullRequestEvent, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal event map into bytes")
+	}
+
+	var event PullRequestEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, errors.Wrap(err, "unmarshal event map into concrete type")
+	}
+
+	return &event, nil
+}
+
+// reMergeGroupHeadRef matches the head ref of a merge group, which contains the number of
+// the pull request at the head of the group, e.g.
+// refs/heads/gh-readonly-queue/main/pr-1230-398f1ef4184001cbbc977fbd3bbd42a5b32c9280.
+var reMergeGroupHeadRef = regexp.MustCompile(`/pr-(\d+)-[0-9a-f]+$`)
+
+// MergeGroup is a type meant for a merge_group payload to be marshaled into. This type
+// can be extended with fields as they become necessary in actions.
+//
+// An example of all the fields that could be added to this type can be found in
+// test/payloads/merge_group.json
+type MergeGroup struct {
+	Action     string `json:"action"`
+	MergeGroup struct {
+		HeadSHA string `json:"head_sha"` // SHA of the merge group commit
+		HeadRef string `json:"head_ref"` // Full ref of the temporary merge queue branch
+		BaseSHA string `json:"base_sha"` // SHA of the base branch the group is built on
+		BaseRef string `json:"base_ref"` // Full ref of the base branch
+	} `json:"merge_group"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// HeadPullRequestNumber returns the number of the pull request at the head of the merge
+// group, parsed from the head ref, and whether or not it could be parsed.
+func (mg *MergeGroup) HeadPullRequestNumber() (int, bool) {
+	matches := reMergeGroupHeadRef.FindStringSubmatch(mg.MergeGroup.HeadRef)
+	if matches == nil {
+		return 0, false
+	}
+
+	number, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// ParseMergeGroupPayload takes a GitHub actions payload and returns a *MergeGroup type
+// with the fields from the payload marshaled into the type.
+func ParseMergeGroupPayload(payload map[string]interface{}) (*MergeGroup, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal event map into bytes")
+	}
+
+	var event MergeGroup
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, errors.Wrap(err, "unmarshal event map into concrete type")
+	}
+
+	return &event, nil
+}
+
 // Create is a type meant for a create payload to be marshaled into. This type can be
 // extended with fields as they become necessary in actions.
 //
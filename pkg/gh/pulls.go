@@ -37,3 +37,51 @@ func ListAllPullRequests(ctx context.Context, client *github.Client, org, repo,
 
 	return pulls, nil
 }
+
+// ListAllPullRequestCommits lists all commits on a given pull request for a given
+// org/repo, paginating through the results.
+func ListAllPullRequestCommits(ctx context.Context, client *github.Client, org, repo string, number int) ([]*github.RepositoryCommit, error) { //nolint:lll // Why: Function signature.
+	commitPage := 1
+	commitsPerPage := 100
+
+	var commits []*github.RepositoryCommit
+	for commitPage != 0 {
+		next, res, err := client.PullRequests.ListCommits(ctx, org, repo, number, &github.ListOptions{
+			Page:    commitPage,
+			PerPage: commitsPerPage,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "list commits for pull request #%d", number)
+		}
+
+		commits = append(commits, next...)
+		commitPage = res.NextPage
+	}
+
+	return commits, nil
+}
+
+// ListAllPullRequestFiles lists the filenames of all files changed by a given pull
+// request for a given org/repo, paginating through the results.
+func ListAllPullRequestFiles(ctx context.Context, client *github.Client, org, repo string, number int) ([]string, error) {
+	filePage := 1
+	filesPerPage := 100
+
+	var files []string
+	for filePage != 0 {
+		next, res, err := client.PullRequests.ListFiles(ctx, org, repo, number, &github.ListOptions{
+			Page:    filePage,
+			PerPage: filesPerPage,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "list files for pull request #%d", number)
+		}
+
+		for i := range next {
+			files = append(files, next[i].GetFilename())
+		}
+		filePage = res.NextPage
+	}
+
+	return files, nil
+}
@@ -0,0 +1,22 @@
+// Copyright 2022 Outreach Corporation. All Rights Reserved.
+
+// Description: This file contains helpers for writing to the summary of the job running an
+// action.
+
+package gh
+
+import (
+	"os"
+
+	actions "github.com/sethvargo/go-githubactions"
+)
+
+// AddStepSummary appends markdown to the job summary. This is a no-op when the action is not
+// running in GitHub Actions (e.g. locally), where go-githubactions would otherwise panic.
+func AddStepSummary(markdown string) {
+	if os.Getenv("GITHUB_STEP_SUMMARY") == "" {
+		return
+	}
+
+	actions.AddStepSummary(markdown)
+}